@@ -4,19 +4,55 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+	"telegram-expense-bot/internal/utils"
 
 	"github.com/joho/godotenv"
 )
 
+// Storage backends selectable through STORAGE_BACKEND
+const (
+	BackendMongo  = "mongo"
+	BackendSQLite = "sqlite"
+	BackendMemory = "memory"
+)
+
 // Config holds all configuration for the application
 type Config struct {
-	TelegramToken string
-	MongoURI      string
-	MongoDB       string
-	ChatID        int64
-	Categories    []string
+	TelegramToken   string
+	StorageBackend  string
+	MongoURI        string
+	MongoDB         string
+	SQLitePath      string
+	ChatID          int64
+	Members         []string // Usernames every expense is shared between
+	Admins          []string // Usernames allowed to /reset and /restore
+	Categories      []string
+	CategorySplits  map[string]string // Default split rule per category name
+	CategoryAliases map[string]string // Lowercase alias to category label, e.g. "costco" to "Groceries 🛒"
+	HomeCurrency    string            // Currency totals are kept in
+	RatesFile       string            // Optional JSON file with exchange rates to the home currency
+	TrashRetention  time.Duration     // How long deleted transactions can be restored before they are purged
+	ReconcileChatID int64             // Chat the reconciler copies messages to when checking they still exist; 0 turns it off
+	Location        *time.Location    // Timezone of the reset schedule and of the periods
+	Cycle           models.Cycle      // Periods transactions are reported and archived by
+	ResetSchedule   string            // Cron expression of the periodic reset, in Location
 }
 
+// defaultTrashRetentionDays applies when TRASH_RETENTION_DAYS is not set
+const defaultTrashRetentionDays = 30
+
+// defaultResetSchedule runs the reset at 9 AM on the 1st, when the cycle is
+// the calendar month. Any other cycle checks every day at 9 AM and resets
+// on the days a period starts.
+const (
+	defaultResetSchedule      = "0 9 1 * *"
+	defaultCycleResetSchedule = "0 9 * * *"
+)
+
 // Load loads configuration from environment variables
 func Load() *Config {
 	err := godotenv.Load()
@@ -30,11 +66,23 @@ func Load() *Config {
 		log.Fatal("Invalid TELEGRAM_CHAT_ID:", err)
 	}
 
+	backend := strings.ToLower(os.Getenv("STORAGE_BACKEND"))
+	if backend == "" {
+		backend = BackendMongo
+	}
+
 	config := &Config{
-		TelegramToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
-		MongoURI:      os.Getenv("MONGODB_URI"),
-		MongoDB:       os.Getenv("MONGODB_DB"),
-		ChatID:        chatID,
+		TelegramToken:  os.Getenv("TELEGRAM_BOT_TOKEN"),
+		StorageBackend: backend,
+		MongoURI:       os.Getenv("MONGODB_URI"),
+		MongoDB:        os.Getenv("MONGODB_DB"),
+		SQLitePath:     os.Getenv("SQLITE_PATH"),
+		ChatID:         chatID,
+		Members:        parseMembers(os.Getenv("MEMBERS")),
+		Admins:         parseMembers(os.Getenv("ADMINS")),
+		CategorySplits: parseCategorySplits(os.Getenv("CATEGORY_SPLITS")),
+		HomeCurrency:   strings.ToUpper(strings.TrimSpace(os.Getenv("HOME_CURRENCY"))),
+		RatesFile:      os.Getenv("RATES_FILE"),
 		Categories: []string{
 			"Groceries 🛒",
 			"Household 🏠",
@@ -45,15 +93,64 @@ func Load() *Config {
 		},
 	}
 
+	config.CategoryAliases = parseCategoryAliases(os.Getenv("CATEGORY_ALIASES"), config.Categories)
+
+	retentionDays := defaultTrashRetentionDays
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		retentionDays, err = strconv.Atoi(value)
+		if err != nil || retentionDays < 0 {
+			log.Fatal("Invalid TRASH_RETENTION_DAYS: ", value)
+		}
+	}
+	config.TrashRetention = time.Duration(retentionDays) * 24 * time.Hour
+
+	if value := os.Getenv("RECONCILE_CHAT_ID"); value != "" {
+		config.ReconcileChatID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Fatal("Invalid RECONCILE_CHAT_ID: ", value)
+		}
+	}
+
+	config.Location = time.Local
+	if value := os.Getenv("RESET_TIMEZONE"); value != "" {
+		config.Location, err = time.LoadLocation(value)
+		if err != nil {
+			log.Fatal("Invalid RESET_TIMEZONE: ", value)
+		}
+	}
+	config.Cycle = parseCycle(os.Getenv("RESET_CYCLE"), os.Getenv("RESET_ANCHOR"), config.Location)
+
+	config.ResetSchedule = os.Getenv("RESET_SCHEDULE")
+	if config.ResetSchedule == "" {
+		config.ResetSchedule = defaultCycleResetSchedule
+		if config.Cycle.Length == models.CycleMonthly && config.Cycle.Anchor.Day() == 1 {
+			config.ResetSchedule = defaultResetSchedule
+		}
+	}
+
 	// Validate required fields
 	if config.TelegramToken == "" {
 		log.Fatal("TELEGRAM_BOT_TOKEN not set")
 	}
-	if config.MongoURI == "" {
-		log.Fatal("MONGODB_URI not set")
+	switch config.StorageBackend {
+	case BackendMongo:
+		if config.MongoURI == "" {
+			log.Fatal("MONGODB_URI not set")
+		}
+		if config.MongoDB == "" {
+			log.Fatal("MONGODB_DB not set")
+		}
+	case BackendSQLite:
+		if config.SQLitePath == "" {
+			config.SQLitePath = "expenses.db"
+		}
+	case BackendMemory:
+		log.Println("Using in-memory storage; data is lost on restart")
+	default:
+		log.Fatal("Unknown STORAGE_BACKEND: ", config.StorageBackend)
 	}
-	if config.MongoDB == "" {
-		log.Fatal("MONGODB_DB not set")
+	if config.HomeCurrency == "" {
+		config.HomeCurrency = "CAD"
 	}
 	if config.ChatID == 0 {
 		log.Fatal("TELEGRAM_CHAT_ID not set")
@@ -66,4 +163,105 @@ func Load() *Config {
 func (c *Config) IsAuthorizedUser(username string, chatID int64) bool {
 	// If the message is from the configured chat, the user is authorized
 	return chatID == c.ChatID
-}
\ No newline at end of file
+}
+
+// IsAdmin checks if the user may run destructive commands like /reset
+func (c *Config) IsAdmin(username string) bool {
+	for _, admin := range c.Admins {
+		if strings.EqualFold(admin, username) {
+			return true
+		}
+	}
+	return false
+}
+
+// CategorySplit returns the default split rule for a category, if any. Rules
+// may name the category with or without its emoji, in any case.
+func (c *Config) CategorySplit(category string) string {
+	name := utils.CategoryName(category)
+	for key, rule := range c.CategorySplits {
+		if strings.EqualFold(key, category) || strings.EqualFold(utils.CategoryName(key), name) {
+			return rule
+		}
+	}
+	return ""
+}
+
+// parseCategorySplits parses "Household=60/40;Dining Out=equal"
+func parseCategorySplits(value string) map[string]string {
+	splits := make(map[string]string)
+	for _, entry := range strings.Split(value, ";") {
+		category, rule, ok := strings.Cut(entry, "=")
+		category, rule = strings.TrimSpace(category), strings.TrimSpace(rule)
+		if !ok || category == "" || rule == "" {
+			continue
+		}
+		splits[category] = rule
+	}
+	return splits
+}
+
+// parseCategoryAliases parses "costco=Groceries;pizza=Dining Out". Aliases
+// for categories that don't exist are dropped with a warning.
+func parseCategoryAliases(value string, categories []string) map[string]string {
+	aliases := make(map[string]string)
+	for _, entry := range strings.Split(value, ";") {
+		alias, name, ok := strings.Cut(entry, "=")
+		alias, name = strings.ToLower(strings.TrimSpace(alias)), strings.TrimSpace(name)
+		if !ok || alias == "" || name == "" {
+			continue
+		}
+		label := ""
+		for _, category := range categories {
+			if strings.EqualFold(category, name) || strings.EqualFold(utils.CategoryName(category), utils.CategoryName(name)) {
+				label = category
+				break
+			}
+		}
+		if label == "" {
+			log.Printf("Ignoring alias %q for unknown category %q", alias, name)
+			continue
+		}
+		aliases[alias] = label
+	}
+	return aliases
+}
+
+// parseCycle parses the period length and the date a period starts on,
+// e.g. "monthly" and "2025-03-15" for periods from the 15th of each month.
+// Without an anchor, months start on the 1st and weeks on a Monday.
+func parseCycle(length, anchor string, loc *time.Location) models.Cycle {
+	length = strings.ToLower(strings.TrimSpace(length))
+	switch length {
+	case "":
+		length = models.CycleMonthly
+	case models.CycleMonthly, models.CycleWeekly, models.CycleBiweekly:
+	default:
+		log.Fatal("Unknown RESET_CYCLE: ", length)
+	}
+
+	cycle := models.Cycle{
+		Length: length,
+		Anchor: time.Date(2024, time.January, 1, 0, 0, 0, 0, loc), // A Monday
+	}
+	if anchor = strings.TrimSpace(anchor); anchor != "" {
+		date, err := time.ParseInLocation("2006-01-02", anchor, loc)
+		if err != nil {
+			log.Fatal("Invalid RESET_ANCHOR, expected a date like 2025-03-15: ", anchor)
+		}
+		cycle.Anchor = date
+	}
+	return cycle
+}
+
+// parseMembers parses a comma-separated list of usernames, with or without @
+func parseMembers(value string) []string {
+	var members []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+		if name != "" {
+			members = append(members, name)
+		}
+	}
+	return members
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+)
+
+func TestRecentArchivesByPeriod(t *testing.T) {
+	ctx := context.Background()
+	march := models.MonthPeriod(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+	april := models.MonthPeriod(march.End)
+
+	testStores(t, func(t *testing.T, store Store) {
+		// April was archived before March, e.g. by a rerun of March's
+		// reset, and the legacy archive has no period at all
+		archives := []models.MonthlyArchive{
+			{ID: "2025-02", ArchivedAt: 300},
+			{ID: april.ID(), PeriodStart: april.Start.Unix(), PeriodEnd: april.End.Unix(), ArchivedAt: 100},
+			{ID: march.ID(), PeriodStart: march.Start.Unix(), PeriodEnd: march.End.Unix(), ArchivedAt: 200},
+		}
+		for i := range archives {
+			if err := store.MoveArchive(ctx, "", &archives[i]); err != nil {
+				t.Fatal("Failed to save archive:", err)
+			}
+		}
+
+		recent, err := store.GetRecentArchives(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recent) != 2 || recent[0].ID != april.ID() || recent[1].ID != march.ID() {
+			t.Errorf("Expected April then March, got %v", archiveIDs(recent))
+		}
+		all, err := store.GetAllArchives(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 3 || all[2].ID != "2025-02" {
+			t.Errorf("Expected the legacy archive last, got %v", archiveIDs(all))
+		}
+	})
+}
+
+// archiveIDs lists the IDs of archives
+func archiveIDs(archives []models.MonthlyArchive) []string {
+	ids := make([]string, 0, len(archives))
+	for _, archive := range archives {
+		ids = append(ids, archive.ID)
+	}
+	return ids
+}
+
+func TestArchivePeriodCountsNewTransactions(t *testing.T) {
+	ctx := context.Background()
+	march := models.MonthPeriod(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+	in := func(days int) int64 { return march.Start.AddDate(0, 0, days).Unix() }
+
+	testStores(t, func(t *testing.T, store Store) {
+		insert(t, store,
+			models.Transaction{ID: "1", Amount: 1000, Author: "alice", Category: "Groceries", CreatedAt: in(1)},
+			models.Transaction{ID: "2", Kind: models.KindSettlement, Amount: 500, Author: "bob", To: "alice", CreatedAt: in(2)},
+		)
+		archive, archived, err := store.ArchivePeriod(ctx, march)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if archived != 2 || archive.TotalTransactions != 1 {
+			t.Errorf("Expected 2 transactions archived and 1 expense, got %d and %d", archived, archive.TotalTransactions)
+		}
+
+		// A transaction backdated into the archived period is added to
+		// its archive and only it counts as new
+		insert(t, store, models.Transaction{ID: "3", Amount: 700, Author: "bob", Category: "Groceries", CreatedAt: in(3)})
+		archive, archived, err = store.ArchivePeriod(ctx, march)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if archived != 1 || len(archive.Transactions) != 3 || archive.TotalTransactions != 2 {
+			t.Errorf("Expected 1 new of 3 transactions and 2 expenses, got %d, %d and %d",
+				archived, len(archive.Transactions), archive.TotalTransactions)
+		}
+	})
+}
+
+func TestSearchLiveAndArchived(t *testing.T) {
+	ctx := context.Background()
+	march := models.MonthPeriod(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+	in := func(days int) int64 { return march.Start.AddDate(0, 0, days).Unix() }
+
+	testStores(t, func(t *testing.T, store Store) {
+		insert(t, store,
+			models.Transaction{ID: "1", Amount: 3000, Author: "alice", Note: "Costco run", CreatedAt: in(1)},
+			models.Transaction{ID: "2", Amount: 1000, Author: "bob", Note: "bus", CreatedAt: in(2)},
+		)
+		if _, _, err := store.ArchivePeriod(ctx, march); err != nil {
+			t.Fatal(err)
+		}
+		// Transaction 2 is live again, e.g. after a restore, and only
+		// its live copy is found
+		insert(t, store,
+			models.Transaction{ID: "2", Amount: 1000, Author: "bob", Note: "bus pass", CreatedAt: in(2)},
+			models.Transaction{ID: "3", Amount: 2000, Author: "bob", Note: "costco", CreatedAt: in(35)},
+		)
+
+		tests := []struct {
+			name   string
+			filter models.SearchFilter
+			want   []string
+		}{
+			{name: "everything", want: []string{"3", "2", "1"}},
+			{name: "words", filter: models.SearchFilter{Words: []string{"costco"}}, want: []string{"3", "1"}},
+			{name: "user", filter: models.SearchFilter{User: "bob"}, want: []string{"3", "2"}},
+			{name: "amount", filter: models.SearchFilter{MinAmount: 2500}, want: []string{"1"}},
+			{name: "dates", filter: models.SearchFilter{From: in(2), Until: march.End.Unix()}, want: []string{"2"}},
+		}
+		for _, tt := range tests {
+			found, err := store.SearchTransactions(ctx, &tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := transactionIDs(found); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+			}
+			for _, tx := range found {
+				if tx.ID == "2" && tx.Note != "bus pass" {
+					t.Errorf("%s: expected the live copy of transaction 2, got %q", tt.name, tx.Note)
+				}
+			}
+		}
+	})
+}
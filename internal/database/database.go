@@ -2,11 +2,13 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"math"
+	"regexp"
 	"time"
 
+	"telegram-expense-bot/internal/ledger"
 	"telegram-expense-bot/internal/models"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -19,8 +21,14 @@ type DB struct {
 	client           *mongo.Client
 	collection       *mongo.Collection
 	archiveCollection *mongo.Collection
+	resetCollection  *mongo.Collection
+	stateCollection  *mongo.Collection
+	leaseCollection  *mongo.Collection
+	members          []string
 }
 
+var _ Store = (*DB)(nil)
+
 // New creates a new database connection
 func New(ctx context.Context, uri, dbName, collName string) (*DB, error) {
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
@@ -37,11 +45,44 @@ func New(ctx context.Context, uri, dbName, collName string) (*DB, error) {
 	archiveCollection := database.Collection("monthly_archives")
 	
 	log.Println("Successfully connected to MongoDB")
-	return &DB{
+	db := &DB{
 		client:           client,
 		collection:       collection,
 		archiveCollection: archiveCollection,
-	}, nil
+		resetCollection:  database.Collection("resets"),
+		stateCollection:  database.Collection("state"),
+		leaseCollection:  database.Collection("leases"),
+	}
+
+	// Without a replica set, archiving and /reset fall back to saving the
+	// copy first and deleting afterwards, which is safe but not atomic
+	if !supportsTransactions(ctx, client) {
+		log.Println("WARNING: MongoDB is not a replica set, so archiving a period and /reset can't run in a transaction. " +
+			"They save their copy before deleting, so nothing is lost, but a crash in between leaves transactions both archived and live. " +
+			"Run MongoDB as a replica set (a single-node one is enough) to make them atomic.")
+	}
+
+	if err = db.migrateMoney(ctx); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	if err = db.createIndexes(ctx); err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+	return db, nil
+}
+
+// archiveOrder lists archives by the period they cover, newest first
+var archiveOrder = bson.D{{Key: "periodStart", Value: -1}, {Key: "_id", Value: -1}}
+
+// createIndexes creates the indexes queries sort by, if missing
+func (db *DB) createIndexes(ctx context.Context) error {
+	_, err := db.archiveCollection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: archiveOrder})
+	if err != nil {
+		return fmt.Errorf("failed to create archive index: %w", err)
+	}
+	return nil
 }
 
 // Close closes the database connection
@@ -49,20 +90,30 @@ func (db *DB) Close(ctx context.Context) error {
 	return db.client.Disconnect(ctx)
 }
 
-// InsertTransaction inserts a new transaction
+// notDeleted matches transactions that are not in the trash
+var notDeleted = bson.M{"deletedAt": bson.M{"$exists": false}}
+
+// InsertTransaction inserts a new transaction, replacing a deleted one
+// with the same ID
 func (db *DB) InsertTransaction(ctx context.Context, tx *models.Transaction) error {
-	tx.CreatedAt = time.Now().Unix()
-	_, err := db.collection.InsertOne(ctx, tx)
+	if tx.CreatedAt == 0 {
+		tx.CreatedAt = time.Now().Unix()
+	}
+	_, err := db.collection.DeleteOne(ctx, bson.M{"_id": tx.ID, "deletedAt": bson.M{"$exists": true}})
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	_, err = db.collection.InsertOne(ctx, tx)
 	if err != nil {
 		return fmt.Errorf("failed to insert transaction: %w", err)
 	}
 	return nil
 }
 
-// FindTransaction finds a transaction by ID
+// FindTransaction finds a transaction by ID, skipping deleted ones
 func (db *DB) FindTransaction(ctx context.Context, id string) (*models.Transaction, error) {
 	var tx models.Transaction
-	err := db.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&tx)
+	err := db.collection.FindOne(ctx, bson.M{"_id": id, "deletedAt": bson.M{"$exists": false}}).Decode(&tx)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -72,28 +123,33 @@ func (db *DB) FindTransaction(ctx context.Context, id string) (*models.Transacti
 	return &tx, nil
 }
 
-// UpdateTransaction updates a transaction
-func (db *DB) UpdateTransaction(ctx context.Context, id string, update bson.M) error {
-	filter := bson.M{"_id": id}
-	_, err := db.collection.UpdateOne(ctx, filter, bson.M{"$set": update})
+// UpdateTransaction changes the fields set in update
+func (db *DB) UpdateTransaction(ctx context.Context, id string, update *Update) error {
+	set := bson.M{}
+	for _, c := range update.changes {
+		set[c.field] = c.value
+	}
+	filter := bson.M{"_id": id, "deletedAt": bson.M{"$exists": false}}
+	_, err := db.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
 	if err != nil {
 		return fmt.Errorf("failed to update transaction: %w", err)
 	}
 	return nil
 }
 
-// DeleteTransaction deletes a transaction by ID
+// DeleteTransaction moves a transaction to the trash
 func (db *DB) DeleteTransaction(ctx context.Context, id string) error {
-	_, err := db.collection.DeleteOne(ctx, bson.M{"_id": id})
+	filter := bson.M{"_id": id, "deletedAt": bson.M{"$exists": false}}
+	_, err := db.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"deletedAt": time.Now().Unix()}})
 	if err != nil {
 		return fmt.Errorf("failed to delete transaction: %w", err)
 	}
 	return nil
 }
 
-// GetAllTransactions returns all transactions
+// GetAllTransactions returns all transactions not deleted
 func (db *DB) GetAllTransactions(ctx context.Context) ([]models.Transaction, error) {
-	cursor, err := db.collection.Find(ctx, bson.M{})
+	cursor, err := db.collection.Find(ctx, notDeleted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
 	}
@@ -111,11 +167,11 @@ func (db *DB) GetAllTransactions(ctx context.Context) ([]models.Transaction, err
 
 // GetRecentTransactions returns recent transactions with limit (0 = no limit)
 func (db *DB) GetRecentTransactions(ctx context.Context, limit int) ([]models.Transaction, error) {
-	opts := options.Find().SetSort(bson.D{{"createdAt", -1}})
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
 	if limit > 0 {
 		opts = opts.SetLimit(int64(limit))
 	}
-	cursor, err := db.collection.Find(ctx, bson.M{}, opts)
+	cursor, err := db.collection.Find(ctx, notDeleted, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch recent transactions: %w", err)
 	}
@@ -131,7 +187,7 @@ func (db *DB) GetRecentTransactions(ctx context.Context, limit int) ([]models.Tr
 	return transactions, nil
 }
 
-// DeleteAllTransactions deletes all transactions
+// DeleteAllTransactions deletes all transactions, including the trash
 func (db *DB) DeleteAllTransactions(ctx context.Context) error {
 	_, err := db.collection.DeleteMany(ctx, bson.M{})
 	if err != nil {
@@ -140,113 +196,223 @@ func (db *DB) DeleteAllTransactions(ctx context.Context) error {
 	return nil
 }
 
-// CalculateTotals calculates user balances and category totals
-func (db *DB) CalculateTotals(ctx context.Context) (float64, map[string]float64, map[string]float64, error) {
-	transactions, err := db.GetAllTransactions(ctx)
+// GetDeletedTransactions returns the transactions in the trash, most
+// recently deleted first
+func (db *DB) GetDeletedTransactions(ctx context.Context) ([]models.Transaction, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+	cursor, err := db.collection.Find(ctx, bson.M{"deletedAt": bson.M{"$exists": true}}, opts)
 	if err != nil {
-		return 0, nil, nil, err
+		return nil, fmt.Errorf("failed to fetch deleted transactions: %w", err)
+	}
+	var deleted []models.Transaction
+	if err := cursor.All(ctx, &deleted); err != nil {
+		return nil, fmt.Errorf("failed to fetch deleted transactions: %w", err)
 	}
+	return deleted, nil
+}
 
-	userTotals := make(map[string]float64)
-	categoryTotals := make(map[string]float64)
+// RestoreTransaction takes a transaction out of the trash. It returns nil
+// when the transaction is not in the trash.
+func (db *DB) RestoreTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	filter := bson.M{"_id": id, "deletedAt": bson.M{"$exists": true}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 
-	for _, tx := range transactions {
-		// Each user's contribution is half the transaction amount
-		absHalf := math.Abs(tx.Amount / 2)
-		userTotals[tx.Author] += absHalf
-		
-		if tx.Category != "" {
-			categoryTotals[tx.Category] += math.Abs(tx.Amount)
+	var tx models.Transaction
+	err := db.collection.FindOneAndUpdate(ctx, filter, bson.M{"$unset": bson.M{"deletedAt": ""}}, opts).Decode(&tx)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("failed to restore transaction: %w", err)
 	}
+	return &tx, nil
+}
 
-	// Calculate net balance (difference between users)
-	var users []string
-	for user := range userTotals {
-		users = append(users, user)
+// PurgeDeletedTransactions permanently removes the transactions deleted
+// before the given Unix time and returns how many there were
+func (db *DB) PurgeDeletedTransactions(ctx context.Context, before int64) (int, error) {
+	result, err := db.collection.DeleteMany(ctx, bson.M{"deletedAt": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted transactions: %w", err)
 	}
-	
-	var balance float64 = 0
-	if len(users) >= 2 {
-		// First user owes positive, second user owes negative
-		balance = userTotals[users[0]] - userTotals[users[1]]
+	return int(result.DeletedCount), nil
+}
+
+// CalculateTotals calculates member balances and category totals
+func (db *DB) CalculateTotals(ctx context.Context) (*models.Totals, error) {
+	transactions, err := db.GetAllTransactions(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return balance, categoryTotals, userTotals, nil
+	return ledger.Compute(transactions, db.members), nil
 }
 
-// ArchiveMonthlyData archives current month's data and returns the archive
-func (db *DB) ArchiveMonthlyData(ctx context.Context) (*models.MonthlyArchive, error) {
-	// Get all current transactions
-	transactions, err := db.GetAllTransactions(ctx)
+// SetMembers sets the household members every expense is shared between
+func (db *DB) SetMembers(members []string) {
+	db.members = members
+}
+
+// ArchivePeriod archives the transactions dated before the end of period,
+// merged into any archive the period already has, and removes exactly
+// those transactions, in one MongoDB transaction. A standalone server
+// can't run transactions; there the archive is saved before the removal,
+// so a failure leaves the transactions in place and a rerun merges the
+// same data again.
+func (db *DB) ArchivePeriod(ctx context.Context, period models.Period) (*models.MonthlyArchive, int, error) {
+	session, err := db.client.StartSession()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var archived int
+	result, err := session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		archive, count, err := db.archivePeriod(sc, period)
+		archived = count
+		return archive, err
+	})
+	if transactionsUnsupported(err) {
+		log.Println("WARNING: MongoDB can't run transactions, archiving without one")
+		return db.archivePeriod(ctx, period)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return result.(*models.MonthlyArchive), archived, nil
+}
+
+// archivePeriod saves the archive of period and then removes the newly
+// archived transactions
+func (db *DB) archivePeriod(ctx context.Context, period models.Period) (*models.MonthlyArchive, int, error) {
+	query := bson.M{
+		"createdAt": bson.M{"$lt": period.End.Unix()},
+		"deletedAt": bson.M{"$exists": false},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := db.collection.Find(ctx, query, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transactions for archive: %w", err)
+		return nil, 0, fmt.Errorf("failed to get transactions for archive: %w", err)
+	}
+	var transactions []models.Transaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, 0, fmt.Errorf("failed to get transactions for archive: %w", err)
 	}
 
 	if len(transactions) == 0 {
-		return nil, fmt.Errorf("no transactions to archive")
+		return nil, 0, ErrNothingToArchive
 	}
 
-	// Calculate totals
-	balance, categoryTotals, userTotals, err := db.CalculateTotals(ctx)
+	var existing *models.MonthlyArchive
+	err = db.archiveCollection.FindOne(ctx, bson.M{"_id": period.ID()}).Decode(&existing)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, 0, fmt.Errorf("failed to read monthly archive: %w", err)
+	}
+
+	merged, err := mergeArchived(existing, transactions, period)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate totals for archive: %w", err)
+		return nil, 0, err
 	}
+	archive := buildArchive(merged, db.members, period, time.Now())
 
-	// Calculate additional stats
-	totalSpent := 0.0
-	highestAmount := 0.0
-	lowestAmount := math.MaxFloat64
-	uniqueDays := make(map[string]bool)
+	// Upsert, since the period may have been archived before
+	replaceOpts := options.ReplaceOptions{}
+	replaceOpts.SetUpsert(true)
+	_, err = db.archiveCollection.ReplaceOne(ctx, bson.M{"_id": archive.ID}, archive, &replaceOpts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to save monthly archive: %w", err)
+	}
 
-	for _, tx := range transactions {
-		amt := math.Abs(tx.Amount)
-		totalSpent += amt
-		
-		if amt > highestAmount {
-			highestAmount = amt
-		}
-		if amt < lowestAmount {
-			lowestAmount = amt
-		}
-		
-		day := time.Unix(tx.CreatedAt, 0).Format("2006-01-02")
-		uniqueDays[day] = true
+	_, err = db.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": transactionIDs(transactions)}})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to clear archived transactions: %w", err)
 	}
+	return archive, len(transactions), nil
+}
 
-	avgTransaction := totalSpent / float64(len(transactions))
+// transactionsUnsupported checks if err comes from a server that can't run
+// multi-document transactions, i.e. one that is not a replica set
+func transactionsUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Name == "IllegalOperation"
+}
 
-	// Create archive record
-	now := time.Now()
-	monthID := now.Format("2006-01")
-	
-	archive := &models.MonthlyArchive{
-		ID:                monthID,
-		Year:              now.Year(),
-		Month:             int(now.Month()),
-		MonthName:         now.Format("January"),
-		TotalSpent:        totalSpent,
-		TotalTransactions: len(transactions),
-		Balance:           balance,
-		UserTotals:        userTotals,
-		CategoryTotals:    categoryTotals,
-		Transactions:      transactions,
-		AvgTransaction:    avgTransaction,
-		HighestTransaction: highestAmount,
-		LowestTransaction:  lowestAmount,
-		DaysWithSpending:  len(uniqueDays),
-		ArchivedAt:        now.Unix(),
+// supportsTransactions checks if the server is a replica set member or a
+// sharded cluster router, the deployments that can run transactions
+func supportsTransactions(ctx context.Context, client *mongo.Client) bool {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
 	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		log.Println("Failed to check the MongoDB deployment:", err)
+		return false
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
 
-	// Insert archive (upsert to handle re-runs)
-	opts := options.ReplaceOptions{}
-	opts.SetUpsert(true)
-	_, err = db.archiveCollection.ReplaceOne(ctx, bson.M{"_id": monthID}, archive, &opts)
+// SearchTransactions returns the live and archived transactions that match
+// filter, newest first. MongoDB narrows both collections down with the
+// filter; amounts are compared afterwards since they depend on the rate.
+func (db *DB) SearchTransactions(ctx context.Context, filter *models.SearchFilter) ([]models.Transaction, error) {
+	query := searchQuery(filter, "")
+	query["deletedAt"] = bson.M{"$exists": false}
+	cursor, err := db.collection.Find(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save monthly archive: %w", err)
+		return nil, fmt.Errorf("failed to search transactions: %w", err)
+	}
+	var live []models.Transaction
+	if err := cursor.All(ctx, &live); err != nil {
+		return nil, fmt.Errorf("failed to search transactions: %w", err)
 	}
 
-	return archive, nil
+	// Unwind the archived transactions so each one is matched on its own
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$transactions"}},
+		{{Key: "$match", Value: searchQuery(filter, "transactions.")}},
+		{{Key: "$group", Value: bson.M{"_id": "$_id", "transactions": bson.M{"$push": "$transactions"}}}},
+	}
+	cursor, err = db.archiveCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search archives: %w", err)
+	}
+	var archives []models.MonthlyArchive
+	if err := cursor.All(ctx, &archives); err != nil {
+		return nil, fmt.Errorf("failed to search archives: %w", err)
+	}
+
+	return searchTransactions(live, archives, filter), nil
+}
+
+// searchQuery builds the MongoDB query for a search filter, with field
+// names under prefix
+func searchQuery(filter *models.SearchFilter, prefix string) bson.M {
+	query := bson.M{}
+	var and []bson.M
+	for _, word := range filter.Words {
+		and = append(and, bson.M{prefix + "note": bson.M{"$regex": regexp.QuoteMeta(word), "$options": "i"}})
+	}
+	if filter.Category != "" {
+		query[prefix+"category"] = filter.Category
+	}
+	if filter.User != "" {
+		and = append(and, bson.M{"$or": []bson.M{{prefix + "author": filter.User}, {prefix + "payer": filter.User}}})
+	}
+	created := bson.M{}
+	if filter.From != 0 {
+		created["$gte"] = filter.From
+	}
+	if filter.Until != 0 {
+		created["$lt"] = filter.Until
+	}
+	if len(created) > 0 {
+		query[prefix+"createdAt"] = created
+	}
+	if len(and) > 0 {
+		query["$and"] = and
+	}
+	return query
 }
 
 // GetMonthlyArchive retrieves archived data for a specific month
@@ -262,9 +428,42 @@ func (db *DB) GetMonthlyArchive(ctx context.Context, monthID string) (*models.Mo
 	return &archive, nil
 }
 
-// GetRecentArchives retrieves the most recent archived months
+// MoveArchive saves archive, whose ID must be free, in place of the
+// archive fromID, in one MongoDB transaction. A standalone server inserts
+// the archive before removing the old one, so a failure leaves a copy
+// rather than losing it.
+func (db *DB) MoveArchive(ctx context.Context, fromID string, archive *models.MonthlyArchive) error {
+	session, err := db.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, db.moveArchive(sc, fromID, archive)
+	})
+	if transactionsUnsupported(err) {
+		log.Println("WARNING: MongoDB can't run transactions, moving the archive without one")
+		return db.moveArchive(ctx, fromID, archive)
+	}
+	return err
+}
+
+// moveArchive inserts archive and then removes the archive fromID
+func (db *DB) moveArchive(ctx context.Context, fromID string, archive *models.MonthlyArchive) error {
+	if _, err := db.archiveCollection.InsertOne(ctx, archive); err != nil {
+		return fmt.Errorf("failed to save monthly archive: %w", err)
+	}
+	if _, err := db.archiveCollection.DeleteOne(ctx, bson.M{"_id": fromID}); err != nil {
+		return fmt.Errorf("failed to remove monthly archive: %w", err)
+	}
+	return nil
+}
+
+// GetRecentArchives retrieves the archives of the most recent periods.
+// Ones from before periods were recorded have no periodStart and come last.
 func (db *DB) GetRecentArchives(ctx context.Context, limit int) ([]models.MonthlyArchive, error) {
-	opts := options.Find().SetSort(bson.D{{"archivedAt", -1}})
+	opts := options.Find().SetSort(archiveOrder)
 	if limit > 0 {
 		opts = opts.SetLimit(int64(limit))
 	}
@@ -289,4 +488,133 @@ func (db *DB) GetRecentArchives(ctx context.Context, limit int) ([]models.Monthl
 // GetAllArchives retrieves all archived months
 func (db *DB) GetAllArchives(ctx context.Context) ([]models.MonthlyArchive, error) {
 	return db.GetRecentArchives(ctx, 0)
-}
\ No newline at end of file
+}
+
+// ResetTransactions copies every transaction, the trash included, into
+// snapshot, saves it and removes exactly those transactions, in one
+// MongoDB transaction. A standalone server saves the snapshot before the
+// removal instead, so a failure never loses a transaction.
+func (db *DB) ResetTransactions(ctx context.Context, snapshot *models.ResetSnapshot) error {
+	if snapshot.CreatedAt == 0 {
+		snapshot.CreatedAt = time.Now().Unix()
+	}
+
+	session, err := db.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, db.resetTransactions(sc, snapshot)
+	})
+	if transactionsUnsupported(err) {
+		log.Println("WARNING: MongoDB can't run transactions, resetting without one")
+		return db.resetTransactions(ctx, snapshot)
+	}
+	return err
+}
+
+// resetTransactions saves the snapshot and then removes the transactions
+// in it
+func (db *DB) resetTransactions(ctx context.Context, snapshot *models.ResetSnapshot) error {
+	cursor, err := db.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to get transactions for reset: %w", err)
+	}
+	snapshot.Transactions = nil
+	if err := cursor.All(ctx, &snapshot.Transactions); err != nil {
+		return fmt.Errorf("failed to get transactions for reset: %w", err)
+	}
+
+	if _, err := db.resetCollection.InsertOne(ctx, snapshot); err != nil {
+		return fmt.Errorf("failed to save reset snapshot: %w", err)
+	}
+
+	_, err = db.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": transactionIDs(snapshot.Transactions)}})
+	if err != nil {
+		return fmt.Errorf("failed to clear transactions: %w", err)
+	}
+	return nil
+}
+
+// GetLatestResetSnapshot returns the most recent reset snapshot, or nil
+// if there is none
+func (db *DB) GetLatestResetSnapshot(ctx context.Context) (*models.ResetSnapshot, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	var snapshot models.ResetSnapshot
+	err := db.resetCollection.FindOne(ctx, bson.M{}, opts).Decode(&snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch reset snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// MarkResetRestored records that a snapshot was restored, reporting false
+// if it already was
+func (db *DB) MarkResetRestored(ctx context.Context, id string, at int64) (bool, error) {
+	filter := bson.M{"_id": id, "restoredAt": bson.M{"$exists": false}}
+	result, err := db.resetCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"restoredAt": at}})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark reset restored: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// GetResetState returns the last period the periodic reset completed, or
+// nil if it never ran
+func (db *DB) GetResetState(ctx context.Context) (*models.ResetState, error) {
+	var state models.ResetState
+	err := db.stateCollection.FindOne(ctx, bson.M{"_id": "periodic"}).Decode(&state)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch reset state: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveResetState records the last period the periodic reset completed
+func (db *DB) SaveResetState(ctx context.Context, state *models.ResetState) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := db.stateCollection.ReplaceOne(ctx, bson.M{"_id": state.ID}, state, opts)
+	if err != nil {
+		return fmt.Errorf("failed to save reset state: %w", err)
+	}
+	return nil
+}
+
+// AcquireLease takes the named lease for owner unless another owner holds
+// it and it hasn't expired. The upsert only matches a lease that can be
+// taken; for one that can't, it tries to insert a second document with the
+// same ID and fails.
+func (db *DB) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": []bson.M{{"owner": owner}, {"expiresAt": bson.M{"$lte": now.Unix()}}},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "expiresAt": now.Add(ttl).Unix()}}
+	_, err := db.leaseCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseLease gives up the named lease if owner still holds it
+func (db *DB) ReleaseLease(ctx context.Context, name, owner string) error {
+	_, err := db.leaseCollection.DeleteOne(ctx, bson.M{"_id": name, "owner": owner})
+	if err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLeases(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		acquire := func(name, owner string, ttl time.Duration, want bool) {
+			t.Helper()
+			if ok, err := store.AcquireLease(ctx, name, owner, ttl); err != nil || ok != want {
+				t.Errorf("%s acquiring %s = %v, %v, want %v", owner, name, ok, err, want)
+			}
+		}
+		release := func(name, owner string) {
+			t.Helper()
+			if err := store.ReleaseLease(ctx, name, owner); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// A held lease keeps others out but can be renewed by its owner,
+		// and leases of other jobs are separate
+		acquire("reset", "a", time.Hour, true)
+		acquire("reset", "b", time.Hour, false)
+		acquire("reset", "a", time.Hour, true)
+		acquire("purge", "b", time.Hour, true)
+
+		// Only the owner can release it
+		release("reset", "b")
+		acquire("reset", "b", time.Hour, false)
+		release("reset", "a")
+		acquire("reset", "b", time.Hour, true)
+
+		// An expired lease is free for anyone, e.g. after a crash
+		acquire("reset", "b", -time.Second, true)
+		acquire("reset", "a", time.Hour, true)
+		acquire("reset", "b", time.Hour, false)
+	})
+}
+
+func TestLeaseContention(t *testing.T) {
+	const runs = 8
+	contend := func(t *testing.T, stores ...Store) {
+		var wg sync.WaitGroup
+		wins := make(chan string, runs)
+		for i := 0; i < runs; i++ {
+			wg.Add(1)
+			go func(store Store, owner string) {
+				defer wg.Done()
+				ok, err := store.AcquireLease(context.Background(), "reset", owner, time.Hour)
+				if err != nil {
+					t.Error(err)
+				}
+				if ok {
+					wins <- owner
+				}
+			}(stores[i%len(stores)], fmt.Sprintf("run-%d", i))
+		}
+		wg.Wait()
+		close(wins)
+
+		var winners []string
+		for owner := range wins {
+			winners = append(winners, owner)
+		}
+		if len(winners) != 1 {
+			t.Errorf("Expected exactly one run to get the lease, got %v", winners)
+		}
+	}
+
+	testStores(t, func(t *testing.T, store Store) {
+		contend(t, store)
+	})
+	// Bot processes sharing a database file each have their own connection
+	t.Run("sqlite processes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "expenses.db")
+		var stores []Store
+		for i := 0; i < 2; i++ {
+			store, err := NewSQLite(context.Background(), path)
+			if err != nil {
+				t.Fatal("Failed to open SQLite:", err)
+			}
+			t.Cleanup(func() { store.Close(context.Background()) })
+			stores = append(stores, store)
+		}
+		contend(t, stores...)
+	})
+}
@@ -0,0 +1,428 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"telegram-expense-bot/internal/ledger"
+	"telegram-expense-bot/internal/models"
+)
+
+// MemoryDB keeps transactions and archives in process memory. Nothing
+// survives a restart, which makes it suitable for tests and trying the bot out.
+type MemoryDB struct {
+	mu           sync.Mutex
+	transactions map[string]models.Transaction
+	order        []string
+	archives     map[string]models.MonthlyArchive
+	resets       []models.ResetSnapshot
+	resetState   *models.ResetState
+	leases       map[string]models.Lease
+	members      []string
+	now          func() time.Time
+}
+
+var _ Store = (*MemoryDB)(nil)
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *MemoryDB {
+	return &MemoryDB{
+		transactions: make(map[string]models.Transaction),
+		archives:     make(map[string]models.MonthlyArchive),
+		leases:       make(map[string]models.Lease),
+		now:          time.Now,
+	}
+}
+
+// SetClock replaces the clock used for CreatedAt and archive timestamps
+func (m *MemoryDB) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+// Close is a no-op for the in-memory store
+func (m *MemoryDB) Close(ctx context.Context) error {
+	return nil
+}
+
+// InsertTransaction inserts a new transaction, replacing a deleted one
+// with the same ID
+func (m *MemoryDB) InsertTransaction(ctx context.Context, tx *models.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, exists := m.transactions[tx.ID]; exists {
+		if existing.DeletedAt == 0 {
+			return fmt.Errorf("failed to insert transaction: duplicate id %s", tx.ID)
+		}
+		m.remove(tx.ID)
+	}
+	if tx.CreatedAt == 0 {
+		tx.CreatedAt = m.now().Unix()
+	}
+	m.transactions[tx.ID] = *tx
+	m.order = append(m.order, tx.ID)
+	return nil
+}
+
+// FindTransaction finds a transaction by ID, skipping deleted ones
+func (m *MemoryDB) FindTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx, ok := m.transactions[id]
+	if !ok || tx.DeletedAt != 0 {
+		return nil, nil
+	}
+	return &tx, nil
+}
+
+// UpdateTransaction changes the fields set in update
+func (m *MemoryDB) UpdateTransaction(ctx context.Context, id string, update *Update) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx, ok := m.transactions[id]
+	if !ok || tx.DeletedAt != 0 {
+		return nil
+	}
+	update.applyTo(&tx)
+	m.transactions[id] = tx
+	return nil
+}
+
+// DeleteTransaction moves a transaction to the trash
+func (m *MemoryDB) DeleteTransaction(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx, ok := m.transactions[id]
+	if !ok || tx.DeletedAt != 0 {
+		return nil
+	}
+	tx.DeletedAt = m.now().Unix()
+	m.transactions[id] = tx
+	return nil
+}
+
+// remove drops a transaction for good; the caller holds the lock
+func (m *MemoryDB) remove(id string) {
+	delete(m.transactions, id)
+	for i, existing := range m.order {
+		if existing == id {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// GetAllTransactions returns all transactions not deleted, in insertion order
+func (m *MemoryDB) GetAllTransactions(ctx context.Context) ([]models.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var transactions []models.Transaction
+	for _, id := range m.order {
+		if tx := m.transactions[id]; tx.DeletedAt == 0 {
+			transactions = append(transactions, tx)
+		}
+	}
+	return transactions, nil
+}
+
+// GetRecentTransactions returns recent transactions with limit (0 = no limit)
+func (m *MemoryDB) GetRecentTransactions(ctx context.Context, limit int) ([]models.Transaction, error) {
+	transactions, _ := m.GetAllTransactions(ctx)
+
+	// Newest first; ties keep the reverse insertion order
+	for i, j := 0, len(transactions)-1; i < j; i, j = i+1, j-1 {
+		transactions[i], transactions[j] = transactions[j], transactions[i]
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].CreatedAt > transactions[j].CreatedAt
+	})
+
+	if limit > 0 && len(transactions) > limit {
+		transactions = transactions[:limit]
+	}
+	return transactions, nil
+}
+
+// DeleteAllTransactions deletes all transactions, including the trash
+func (m *MemoryDB) DeleteAllTransactions(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.transactions = make(map[string]models.Transaction)
+	m.order = nil
+	return nil
+}
+
+// GetDeletedTransactions returns the transactions in the trash, most
+// recently deleted first
+func (m *MemoryDB) GetDeletedTransactions(ctx context.Context) ([]models.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Ties keep the reverse insertion order
+	var deleted []models.Transaction
+	for i := len(m.order) - 1; i >= 0; i-- {
+		if tx := m.transactions[m.order[i]]; tx.DeletedAt != 0 {
+			deleted = append(deleted, tx)
+		}
+	}
+	sortDeleted(deleted)
+	return deleted, nil
+}
+
+// RestoreTransaction takes a transaction out of the trash. It returns nil
+// when the transaction is not in the trash.
+func (m *MemoryDB) RestoreTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx, ok := m.transactions[id]
+	if !ok || tx.DeletedAt == 0 {
+		return nil, nil
+	}
+	tx.DeletedAt = 0
+	m.transactions[id] = tx
+	return &tx, nil
+}
+
+// PurgeDeletedTransactions permanently removes the transactions deleted
+// before the given Unix time and returns how many there were
+func (m *MemoryDB) PurgeDeletedTransactions(ctx context.Context, before int64) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged []string
+	for id, tx := range m.transactions {
+		if tx.DeletedAt != 0 && tx.DeletedAt < before {
+			purged = append(purged, id)
+		}
+	}
+	for _, id := range purged {
+		m.remove(id)
+	}
+	return len(purged), nil
+}
+
+// CalculateTotals calculates member balances and category totals
+func (m *MemoryDB) CalculateTotals(ctx context.Context) (*models.Totals, error) {
+	transactions, err := m.GetAllTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ledger.Compute(transactions, m.members), nil
+}
+
+// SetMembers sets the household members every expense is shared between
+func (m *MemoryDB) SetMembers(members []string) {
+	m.members = members
+}
+
+// ArchivePeriod archives the transactions dated before the end of period,
+// merged into any archive the period already has, and removes exactly
+// those transactions, in one step under the store's lock
+func (m *MemoryDB) ArchivePeriod(ctx context.Context, period models.Period) (*models.MonthlyArchive, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var transactions []models.Transaction
+	for _, id := range m.order {
+		if tx := m.transactions[id]; tx.DeletedAt == 0 && tx.CreatedAt < period.End.Unix() {
+			transactions = append(transactions, tx)
+		}
+	}
+	if len(transactions) == 0 {
+		return nil, 0, ErrNothingToArchive
+	}
+
+	var existing *models.MonthlyArchive
+	if archive, ok := m.archives[period.ID()]; ok {
+		existing = &archive
+	}
+	merged, err := mergeArchived(existing, transactions, period)
+	if err != nil {
+		return nil, 0, err
+	}
+	archive := buildArchive(merged, m.members, period, m.now())
+	m.archives[archive.ID] = *archive
+	for _, id := range transactionIDs(transactions) {
+		m.remove(id)
+	}
+	return archive, len(transactions), nil
+}
+
+// SearchTransactions returns the live and archived transactions that match
+// filter, newest first
+func (m *MemoryDB) SearchTransactions(ctx context.Context, filter *models.SearchFilter) ([]models.Transaction, error) {
+	live, err := m.GetAllTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	archives, err := m.GetAllArchives(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return searchTransactions(live, archives, filter), nil
+}
+
+// GetMonthlyArchive retrieves archived data for a specific month
+func (m *MemoryDB) GetMonthlyArchive(ctx context.Context, monthID string) (*models.MonthlyArchive, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	archive, ok := m.archives[monthID]
+	if !ok {
+		return nil, fmt.Errorf("no archive found for month %s", monthID)
+	}
+	return &archive, nil
+}
+
+// MoveArchive saves archive, whose ID must be free, in place of the
+// archive fromID
+func (m *MemoryDB) MoveArchive(ctx context.Context, fromID string, archive *models.MonthlyArchive) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.archives[archive.ID]; ok {
+		return fmt.Errorf("archive %s already exists", archive.ID)
+	}
+	m.archives[archive.ID] = *archive
+	delete(m.archives, fromID)
+	return nil
+}
+
+// GetRecentArchives retrieves the archives of the most recent periods
+func (m *MemoryDB) GetRecentArchives(ctx context.Context, limit int) ([]models.MonthlyArchive, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var archives []models.MonthlyArchive
+	for _, archive := range m.archives {
+		archives = append(archives, archive)
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		if archives[i].PeriodStart != archives[j].PeriodStart {
+			return archives[i].PeriodStart > archives[j].PeriodStart
+		}
+		return archives[i].ID > archives[j].ID
+	})
+
+	if limit > 0 && len(archives) > limit {
+		archives = archives[:limit]
+	}
+	return archives, nil
+}
+
+// GetAllArchives retrieves all archived months
+func (m *MemoryDB) GetAllArchives(ctx context.Context) ([]models.MonthlyArchive, error) {
+	return m.GetRecentArchives(ctx, 0)
+}
+
+// ResetTransactions copies every transaction, the trash included, into
+// snapshot, saves it and removes exactly those transactions, in one step
+// under the store's lock
+func (m *MemoryDB) ResetTransactions(ctx context.Context, snapshot *models.ResetSnapshot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if snapshot.CreatedAt == 0 {
+		snapshot.CreatedAt = m.now().Unix()
+	}
+	snapshot.Transactions = nil
+	for _, id := range m.order {
+		snapshot.Transactions = append(snapshot.Transactions, m.transactions[id])
+	}
+	m.resets = append(m.resets, *snapshot)
+	for _, id := range transactionIDs(snapshot.Transactions) {
+		m.remove(id)
+	}
+	return nil
+}
+
+// GetLatestResetSnapshot returns the most recent reset snapshot, or nil
+// if there is none
+func (m *MemoryDB) GetLatestResetSnapshot(ctx context.Context) (*models.ResetSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.resets) == 0 {
+		return nil, nil
+	}
+	snapshot := m.resets[len(m.resets)-1]
+	return &snapshot, nil
+}
+
+// MarkResetRestored records that a snapshot was restored, reporting false
+// if it already was
+func (m *MemoryDB) MarkResetRestored(ctx context.Context, id string, at int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.resets {
+		if m.resets[i].ID == id {
+			if m.resets[i].RestoredAt != 0 {
+				return false, nil
+			}
+			m.resets[i].RestoredAt = at
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("no reset snapshot %s", id)
+}
+
+// GetResetState returns the last period the periodic reset completed, or
+// nil if it never ran
+func (m *MemoryDB) GetResetState(ctx context.Context) (*models.ResetState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.resetState == nil {
+		return nil, nil
+	}
+	state := *m.resetState
+	return &state, nil
+}
+
+// SaveResetState records the last period the periodic reset completed
+func (m *MemoryDB) SaveResetState(ctx context.Context, state *models.ResetState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *state
+	m.resetState = &saved
+	return nil
+}
+
+// AcquireLease takes the named lease for owner unless another owner holds
+// it and it hasn't expired
+func (m *MemoryDB) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if lease, ok := m.leases[name]; ok && lease.Owner != owner && lease.ExpiresAt > now.Unix() {
+		return false, nil
+	}
+	m.leases[name] = models.Lease{ID: name, Owner: owner, ExpiresAt: now.Add(ttl).Unix()}
+	return true, nil
+}
+
+// ReleaseLease gives up the named lease if owner still holds it
+func (m *MemoryDB) ReleaseLease(ctx context.Context, name, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.leases[name].Owner == owner {
+		delete(m.leases, name)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Amounts used to be stored as float64 major units (25.5) and are now
+// integer cents (2550). The helpers below rewrite a legacy document in
+// place; they work on both decoded BSON and decoded JSON documents.
+
+// archiveMoneyFields are the archive fields holding a single amount
+var archiveMoneyFields = []string{
+	"totalSpent", "totalRefunded", "totalIncome", "totalSettled", "balance",
+	"avgTransaction", "highestTransaction", "lowestTransaction",
+}
+
+// balanceMoneyFields are the amounts of an archived member balance
+var balanceMoneyFields = []string{"paid", "share", "settled", "net"}
+
+// legacyTransactionToCents converts a legacy transaction document
+func legacyTransactionToCents(doc map[string]interface{}) {
+	convertFields(doc, "amount")
+}
+
+// legacyArchiveToCents converts a legacy archive document, including the
+// balances and transactions embedded in it
+func legacyArchiveToCents(doc map[string]interface{}) {
+	convertFields(doc, archiveMoneyFields...)
+
+	for _, key := range []string{"userTotals", "categoryTotals"} {
+		if totals, ok := asMap(doc[key]); ok {
+			for name := range totals {
+				convertFields(totals, name)
+			}
+		}
+	}
+
+	if balances, ok := asSlice(doc["balances"]); ok {
+		for _, b := range balances {
+			if balance, ok := asMap(b); ok {
+				convertFields(balance, balanceMoneyFields...)
+			}
+		}
+	}
+
+	if transactions, ok := asSlice(doc["transactions"]); ok {
+		for _, t := range transactions {
+			if tx, ok := asMap(t); ok {
+				legacyTransactionToCents(tx)
+			}
+		}
+	}
+}
+
+// convertFields turns the named float amounts into cents. Values that are
+// already integers are left alone.
+func convertFields(doc map[string]interface{}, keys ...string) {
+	for _, key := range keys {
+		if amount, ok := doc[key].(float64); ok {
+			doc[key] = int64(math.Round(amount * 100))
+		}
+	}
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case bson.M:
+		return m, true
+	}
+	return nil, false
+}
+
+func asSlice(v interface{}) ([]interface{}, bool) {
+	switch s := v.(type) {
+	case []interface{}:
+		return s, true
+	case bson.A:
+		return s, true
+	}
+	return nil, false
+}
+
+// migrateMoney converts transactions and archives still holding float
+// amounts. Legacy documents are recognised by their amounts being stored as
+// doubles, so the migration only ever touches each document once and can be
+// interrupted and rerun safely.
+func (db *DB) migrateMoney(ctx context.Context) error {
+	count, err := convertCollection(ctx, db.collection, bson.M{"amount": bson.M{"$type": "double"}}, legacyTransactionToCents)
+	if err != nil {
+		return fmt.Errorf("failed to migrate transaction amounts: %w", err)
+	}
+	if count > 0 {
+		log.Printf("Converted %d transactions to integer cents", count)
+	}
+
+	count, err = convertCollection(ctx, db.archiveCollection, bson.M{"totalSpent": bson.M{"$type": "double"}}, legacyArchiveToCents)
+	if err != nil {
+		return fmt.Errorf("failed to migrate archive amounts: %w", err)
+	}
+	if count > 0 {
+		log.Printf("Converted %d monthly archives to integer cents", count)
+	}
+	return nil
+}
+
+// convertCollection rewrites every document matching filter with convert,
+// replacing each one as a whole
+func convertCollection(ctx context.Context, collection *mongo.Collection, filter bson.M, convert func(map[string]interface{})) (int, error) {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return count, err
+		}
+		// Only replace the document if it is still the legacy version
+		match := bson.M{"_id": doc["_id"]}
+		for key, value := range filter {
+			match[key] = value
+		}
+		convert(doc)
+		if _, err := collection.ReplaceOne(ctx, match, doc); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, cursor.Err()
+}
@@ -0,0 +1,704 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"telegram-expense-bot/internal/ledger"
+	"telegram-expense-bot/internal/models"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteDB stores transactions and monthly archives in a single SQLite file.
+// Records are kept as JSON documents next to the columns used for ordering,
+// so they mirror the MongoDB documents one to one.
+type SQLiteDB struct {
+	db      *sql.DB
+	members []string
+}
+
+var _ Store = (*SQLiteDB)(nil)
+
+// sqliteMigration is one schema or data change: SQL to execute, a Go
+// function to run, or both
+type sqliteMigration struct {
+	sql string
+	run func(ctx context.Context, dbTx *sql.Tx) error
+}
+
+// sqliteMigrations are applied in order, each exactly once. Append new
+// migrations to the end of the list; never edit one that has shipped.
+var sqliteMigrations = []sqliteMigration{
+	// 1: transactions and monthly archives
+	{sql: `CREATE TABLE transactions (
+		id         TEXT PRIMARY KEY,
+		created_at INTEGER NOT NULL,
+		data       TEXT NOT NULL
+	);
+	CREATE INDEX idx_transactions_created_at ON transactions (created_at);
+	CREATE TABLE monthly_archives (
+		id          TEXT PRIMARY KEY,
+		archived_at INTEGER NOT NULL,
+		data        TEXT NOT NULL
+	);
+	CREATE INDEX idx_monthly_archives_archived_at ON monthly_archives (archived_at);`},
+	// 2: amounts as integer cents instead of float major units
+	{run: sqliteMoneyToCents},
+	// 3: soft delete, with deleted transactions kept in the trash
+	{sql: `ALTER TABLE transactions ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX idx_transactions_deleted_at ON transactions (deleted_at);`},
+	// 4: snapshots taken before /reset
+	{sql: `CREATE TABLE resets (
+		id         TEXT PRIMARY KEY,
+		created_at INTEGER NOT NULL,
+		data       TEXT NOT NULL
+	);
+	CREATE INDEX idx_resets_created_at ON resets (created_at);`},
+	// 5: the last period the periodic reset completed
+	{sql: `CREATE TABLE state (
+		id   TEXT PRIMARY KEY,
+		data TEXT NOT NULL
+	);`},
+	// 6: leases, so one process at a time runs the periodic reset
+	{sql: `CREATE TABLE leases (
+		id         TEXT PRIMARY KEY,
+		owner      TEXT NOT NULL,
+		expires_at INTEGER NOT NULL
+	);`},
+	// 7: archives listed by the period they cover rather than when they
+	// were made; ones from before periods were recorded have none and
+	// come last
+	{sql: `ALTER TABLE monthly_archives ADD COLUMN period_start INTEGER NOT NULL DEFAULT 0;
+	UPDATE monthly_archives SET period_start = COALESCE(json_extract(data, '$.periodStart'), 0);
+	CREATE INDEX idx_monthly_archives_period_start ON monthly_archives (period_start, id);`},
+}
+
+// NewSQLite opens (creating if needed) the SQLite database at path and
+// brings its schema up to date
+func NewSQLite(ctx context.Context, path string) (*SQLiteDB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// A single connection serialises writers and keeps the file lock simple
+	db.SetMaxOpenConns(1)
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	s := &SQLiteDB{db: db}
+	if err = s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Printf("Using SQLite database at %s", path)
+	return s, nil
+}
+
+// migrate applies any pending schema migrations
+func (s *SQLiteDB) migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	err = s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := current; i < len(sqliteMigrations); i++ {
+		version := i + 1
+		dbTx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to start migration %d: %w", version, err)
+		}
+		migration := sqliteMigrations[i]
+		if migration.sql != "" {
+			if _, err = dbTx.ExecContext(ctx, migration.sql); err != nil {
+				dbTx.Rollback()
+				return fmt.Errorf("failed to apply migration %d: %w", version, err)
+			}
+		}
+		if migration.run != nil {
+			if err = migration.run(ctx, dbTx); err != nil {
+				dbTx.Rollback()
+				return fmt.Errorf("failed to apply migration %d: %w", version, err)
+			}
+		}
+		if _, err = dbTx.ExecContext(ctx, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", version, time.Now().Unix()); err != nil {
+			dbTx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version, err)
+		}
+		if err = dbTx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version, err)
+		}
+		log.Printf("Applied SQLite migration %d", version)
+	}
+	return nil
+}
+
+// sqliteMoneyToCents rewrites every stored transaction and archive with
+// amounts in integer cents
+func sqliteMoneyToCents(ctx context.Context, dbTx *sql.Tx) error {
+	if err := convertTable(ctx, dbTx, "transactions", legacyTransactionToCents); err != nil {
+		return err
+	}
+	return convertTable(ctx, dbTx, "monthly_archives", legacyArchiveToCents)
+}
+
+// convertTable rewrites the JSON document of every row in table
+func convertTable(ctx context.Context, dbTx *sql.Tx, table string, convert func(map[string]interface{})) error {
+	rows, err := dbTx.QueryContext(ctx, "SELECT id, data FROM "+table)
+	if err != nil {
+		return err
+	}
+	converted := make(map[string]string)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			rows.Close()
+			return err
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to decode %s %s: %w", table, id, err)
+		}
+		convert(doc)
+		encoded, err := json.Marshal(doc)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		converted[id] = string(encoded)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, data := range converted {
+		if _, err := dbTx.ExecContext(ctx, "UPDATE "+table+" SET data = ? WHERE id = ?", data, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database file
+func (s *SQLiteDB) Close(ctx context.Context) error {
+	return s.db.Close()
+}
+
+// InsertTransaction inserts a new transaction, replacing a deleted one
+// with the same ID
+func (s *SQLiteDB) InsertTransaction(ctx context.Context, tx *models.Transaction) error {
+	if tx.CreatedAt == 0 {
+		tx.CreatedAt = time.Now().Unix()
+	}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM transactions WHERE id = ? AND deleted_at != 0", tx.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT INTO transactions (id, created_at, deleted_at, data) VALUES (?, ?, ?, ?)", tx.ID, tx.CreatedAt, tx.DeletedAt, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	return nil
+}
+
+// FindTransaction finds a transaction by ID, skipping deleted ones
+func (s *SQLiteDB) FindTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM transactions WHERE id = ? AND deleted_at = 0", id).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find transaction: %w", err)
+	}
+
+	var tx models.Transaction
+	if err := json.Unmarshal([]byte(data), &tx); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	return &tx, nil
+}
+
+// UpdateTransaction changes the fields set in update
+func (s *SQLiteDB) UpdateTransaction(ctx context.Context, id string, update *Update) error {
+	tx, err := s.FindTransaction(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to update transaction: %w", err)
+	}
+	if tx == nil {
+		return nil
+	}
+
+	update.applyTo(tx)
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("failed to update transaction: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, "UPDATE transactions SET created_at = ?, data = ? WHERE id = ?", tx.CreatedAt, string(data), id)
+	if err != nil {
+		return fmt.Errorf("failed to update transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteTransaction moves a transaction to the trash
+func (s *SQLiteDB) DeleteTransaction(ctx context.Context, id string) error {
+	tx, err := s.FindTransaction(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete transaction: %w", err)
+	}
+	if tx == nil {
+		return nil
+	}
+
+	tx.DeletedAt = time.Now().Unix()
+	if err := s.saveDeletedAt(ctx, tx); err != nil {
+		return fmt.Errorf("failed to delete transaction: %w", err)
+	}
+	return nil
+}
+
+// saveDeletedAt stores the trash state of tx in both its row and document
+func (s *SQLiteDB) saveDeletedAt(ctx context.Context, tx *models.Transaction) error {
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "UPDATE transactions SET deleted_at = ?, data = ? WHERE id = ?", tx.DeletedAt, string(data), tx.ID)
+	return err
+}
+
+// GetAllTransactions returns all transactions not deleted
+func (s *SQLiteDB) GetAllTransactions(ctx context.Context) ([]models.Transaction, error) {
+	transactions, err := s.queryTransactions(ctx, "SELECT data FROM transactions WHERE deleted_at = 0 ORDER BY created_at, rowid")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// GetRecentTransactions returns recent transactions with limit (0 = no limit)
+func (s *SQLiteDB) GetRecentTransactions(ctx context.Context, limit int) ([]models.Transaction, error) {
+	query := "SELECT data FROM transactions WHERE deleted_at = 0 ORDER BY created_at DESC, rowid DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	transactions, err := s.queryTransactions(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recent transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// queryTransactions decodes every transaction document returned by query
+func (s *SQLiteDB) queryTransactions(ctx context.Context, query string, args ...interface{}) ([]models.Transaction, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTransactions(rows)
+}
+
+// decodeTransactions decodes the transaction documents of rows and closes
+// them
+func decodeTransactions(rows *sql.Rows) ([]models.Transaction, error) {
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var tx models.Transaction
+		if err := json.Unmarshal([]byte(data), &tx); err == nil {
+			transactions = append(transactions, tx)
+		}
+	}
+	return transactions, rows.Err()
+}
+
+// DeleteAllTransactions deletes all transactions, including the trash
+func (s *SQLiteDB) DeleteAllTransactions(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM transactions")
+	if err != nil {
+		return fmt.Errorf("failed to delete all transactions: %w", err)
+	}
+	return nil
+}
+
+// GetDeletedTransactions returns the transactions in the trash, most
+// recently deleted first
+func (s *SQLiteDB) GetDeletedTransactions(ctx context.Context) ([]models.Transaction, error) {
+	transactions, err := s.queryTransactions(ctx, "SELECT data FROM transactions WHERE deleted_at != 0 ORDER BY deleted_at DESC, rowid DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deleted transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// RestoreTransaction takes a transaction out of the trash. It returns nil
+// when the transaction is not in the trash.
+func (s *SQLiteDB) RestoreTransaction(ctx context.Context, id string) (*models.Transaction, error) {
+	transactions, err := s.queryTransactions(ctx, "SELECT data FROM transactions WHERE id = ? AND deleted_at != 0", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore transaction: %w", err)
+	}
+	if len(transactions) == 0 {
+		return nil, nil
+	}
+
+	tx := &transactions[0]
+	tx.DeletedAt = 0
+	if err := s.saveDeletedAt(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to restore transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// PurgeDeletedTransactions permanently removes the transactions deleted
+// before the given Unix time and returns how many there were
+func (s *SQLiteDB) PurgeDeletedTransactions(ctx context.Context, before int64) (int, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM transactions WHERE deleted_at != 0 AND deleted_at < ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted transactions: %w", err)
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), nil
+}
+
+// CalculateTotals calculates member balances and category totals
+func (s *SQLiteDB) CalculateTotals(ctx context.Context) (*models.Totals, error) {
+	transactions, err := s.GetAllTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ledger.Compute(transactions, s.members), nil
+}
+
+// SetMembers sets the household members every expense is shared between
+func (s *SQLiteDB) SetMembers(members []string) {
+	s.members = members
+}
+
+// ArchivePeriod archives the transactions dated before the end of period,
+// merged into any archive the period already has, and removes exactly
+// those transactions, in one SQLite transaction
+func (s *SQLiteDB) ArchivePeriod(ctx context.Context, period models.Period) (*models.MonthlyArchive, int, error) {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to archive: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	rows, err := dbTx.QueryContext(ctx, "SELECT data FROM transactions WHERE deleted_at = 0 AND created_at < ? ORDER BY created_at, rowid",
+		period.End.Unix())
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get transactions for archive: %w", err)
+	}
+	transactions, err := decodeTransactions(rows)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get transactions for archive: %w", err)
+	}
+
+	if len(transactions) == 0 {
+		return nil, 0, ErrNothingToArchive
+	}
+
+	var existing *models.MonthlyArchive
+	var stored string
+	err = dbTx.QueryRowContext(ctx, "SELECT data FROM monthly_archives WHERE id = ?", period.ID()).Scan(&stored)
+	switch {
+	case err == nil:
+		existing = &models.MonthlyArchive{}
+		if err := json.Unmarshal([]byte(stored), existing); err != nil {
+			return nil, 0, fmt.Errorf("failed to read monthly archive: %w", err)
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, 0, fmt.Errorf("failed to read monthly archive: %w", err)
+	}
+
+	merged, err := mergeArchived(existing, transactions, period)
+	if err != nil {
+		return nil, 0, err
+	}
+	archive := buildArchive(merged, s.members, period, time.Now())
+
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to save monthly archive: %w", err)
+	}
+
+	_, err = dbTx.ExecContext(ctx, `INSERT INTO monthly_archives (id, archived_at, period_start, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET archived_at = excluded.archived_at, period_start = excluded.period_start, data = excluded.data`,
+		archive.ID, archive.ArchivedAt, archive.PeriodStart, string(data))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to save monthly archive: %w", err)
+	}
+
+	for _, id := range transactionIDs(transactions) {
+		if _, err := dbTx.ExecContext(ctx, "DELETE FROM transactions WHERE id = ?", id); err != nil {
+			return nil, 0, fmt.Errorf("failed to clear archived transactions: %w", err)
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("failed to archive: %w", err)
+	}
+	return archive, len(transactions), nil
+}
+
+// SearchTransactions returns the live and archived transactions that match
+// filter, newest first
+func (s *SQLiteDB) SearchTransactions(ctx context.Context, filter *models.SearchFilter) ([]models.Transaction, error) {
+	live, err := s.GetAllTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	archives, err := s.GetAllArchives(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return searchTransactions(live, archives, filter), nil
+}
+
+// GetMonthlyArchive retrieves archived data for a specific month
+func (s *SQLiteDB) GetMonthlyArchive(ctx context.Context, monthID string) (*models.MonthlyArchive, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM monthly_archives WHERE id = ?", monthID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no archive found for month %s", monthID)
+		}
+		return nil, fmt.Errorf("failed to retrieve archive: %w", err)
+	}
+
+	var archive models.MonthlyArchive
+	if err := json.Unmarshal([]byte(data), &archive); err != nil {
+		return nil, fmt.Errorf("failed to decode archive: %w", err)
+	}
+	return &archive, nil
+}
+
+// MoveArchive saves archive, whose ID must be free, in place of the
+// archive fromID, in one SQLite transaction
+func (s *SQLiteDB) MoveArchive(ctx context.Context, fromID string, archive *models.MonthlyArchive) error {
+	data, err := json.Marshal(archive)
+	if err != nil {
+		return fmt.Errorf("failed to save monthly archive: %w", err)
+	}
+
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to move archive: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	_, err = dbTx.ExecContext(ctx, "INSERT INTO monthly_archives (id, archived_at, period_start, data) VALUES (?, ?, ?, ?)",
+		archive.ID, archive.ArchivedAt, archive.PeriodStart, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save monthly archive: %w", err)
+	}
+	if _, err := dbTx.ExecContext(ctx, "DELETE FROM monthly_archives WHERE id = ?", fromID); err != nil {
+		return fmt.Errorf("failed to remove monthly archive: %w", err)
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to move archive: %w", err)
+	}
+	return nil
+}
+
+// GetRecentArchives retrieves the archives of the most recent periods
+func (s *SQLiteDB) GetRecentArchives(ctx context.Context, limit int) ([]models.MonthlyArchive, error) {
+	query := "SELECT data FROM monthly_archives ORDER BY period_start DESC, id DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch recent archives: %w", err)
+	}
+	defer rows.Close()
+
+	var archives []models.MonthlyArchive
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to fetch recent archives: %w", err)
+		}
+		var archive models.MonthlyArchive
+		if err := json.Unmarshal([]byte(data), &archive); err == nil {
+			archives = append(archives, archive)
+		}
+	}
+
+	return archives, rows.Err()
+}
+
+// GetAllArchives retrieves all archived months
+func (s *SQLiteDB) GetAllArchives(ctx context.Context) ([]models.MonthlyArchive, error) {
+	return s.GetRecentArchives(ctx, 0)
+}
+
+// ResetTransactions copies every transaction, the trash included, into
+// snapshot, saves it and removes exactly those transactions, in one SQLite
+// transaction
+func (s *SQLiteDB) ResetTransactions(ctx context.Context, snapshot *models.ResetSnapshot) error {
+	if snapshot.CreatedAt == 0 {
+		snapshot.CreatedAt = time.Now().Unix()
+	}
+
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to reset: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	rows, err := dbTx.QueryContext(ctx, "SELECT data FROM transactions ORDER BY created_at, rowid")
+	if err != nil {
+		return fmt.Errorf("failed to get transactions for reset: %w", err)
+	}
+	snapshot.Transactions, err = decodeTransactions(rows)
+	if err != nil {
+		return fmt.Errorf("failed to get transactions for reset: %w", err)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to save reset snapshot: %w", err)
+	}
+	_, err = dbTx.ExecContext(ctx, "INSERT INTO resets (id, created_at, data) VALUES (?, ?, ?)", snapshot.ID, snapshot.CreatedAt, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save reset snapshot: %w", err)
+	}
+
+	for _, id := range transactionIDs(snapshot.Transactions) {
+		if _, err := dbTx.ExecContext(ctx, "DELETE FROM transactions WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to clear transactions: %w", err)
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("failed to reset: %w", err)
+	}
+	return nil
+}
+
+// GetLatestResetSnapshot returns the most recent reset snapshot, or nil
+// if there is none
+func (s *SQLiteDB) GetLatestResetSnapshot(ctx context.Context) (*models.ResetSnapshot, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM resets ORDER BY created_at DESC, rowid DESC LIMIT 1").Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch reset snapshot: %w", err)
+	}
+
+	var snapshot models.ResetSnapshot
+	if err := json.Unmarshal([]byte(data), &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode reset snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// MarkResetRestored records that a snapshot was restored, reporting false
+// if it already was
+func (s *SQLiteDB) MarkResetRestored(ctx context.Context, id string, at int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE resets SET data = json_set(data, '$.restoredAt', ?)
+		WHERE id = ? AND json_extract(data, '$.restoredAt') IS NULL`, at, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark reset restored: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark reset restored: %w", err)
+	}
+	return n == 1, nil
+}
+
+// GetResetState returns the last period the periodic reset completed, or
+// nil if it never ran
+func (s *SQLiteDB) GetResetState(ctx context.Context) (*models.ResetState, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM state WHERE id = 'periodic'").Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch reset state: %w", err)
+	}
+
+	var state models.ResetState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, fmt.Errorf("failed to decode reset state: %w", err)
+	}
+	return &state, nil
+}
+
+// SaveResetState records the last period the periodic reset completed
+func (s *SQLiteDB) SaveResetState(ctx context.Context, state *models.ResetState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to save reset state: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO state (id, data) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data`, state.ID, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save reset state: %w", err)
+	}
+	return nil
+}
+
+// AcquireLease takes the named lease for owner unless another owner holds
+// it and it hasn't expired
+func (s *SQLiteDB) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	result, err := s.db.ExecContext(ctx, `INSERT INTO leases (id, owner, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE leases.owner = excluded.owner OR leases.expires_at <= ?`,
+		name, owner, now.Add(ttl).Unix(), now.Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// ReleaseLease gives up the named lease if owner still holds it
+func (s *SQLiteDB) ReleaseLease(ctx context.Context, name, owner string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM leases WHERE id = ? AND owner = ?", name, owner)
+	if err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+)
+
+// openSQLite opens a SQLite store in memory, closed when the test ends
+func openSQLite(t *testing.T) *SQLiteDB {
+	t.Helper()
+	store, err := NewSQLite(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatal("Failed to open SQLite:", err)
+	}
+	t.Cleanup(func() { store.Close(context.Background()) })
+	store.SetMembers(testMembers)
+	return store
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "expenses.db")
+
+	// A database from the first release, with amounts in float units
+	legacy, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		sqliteMigrations[0].sql,
+		`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, applied_at INTEGER NOT NULL)`,
+		`INSERT INTO schema_migrations (version, applied_at) VALUES (1, 0)`,
+		`INSERT INTO transactions (id, created_at, data) VALUES ('1', 1741942800,
+			'{"id":"1","amount":25.5,"author":"alice","category":"Groceries","createdAt":1741942800}')`,
+		`INSERT INTO monthly_archives (id, archived_at, data) VALUES ('2025-02', 1740819600,
+			'{"id":"2025-02","year":2025,"month":2,"monthName":"February","totalSpent":12.99,"totalTransactions":1,
+			"userTotals":{"bob":12.99},"categoryTotals":{"Groceries":12.99},
+			"balances":[{"user":"bob","paid":12.99,"share":6.5,"net":6.49}],
+			"transactions":[{"id":"7","amount":12.99,"author":"bob","createdAt":1739000000}],"archivedAt":1740819600}')`,
+		`INSERT INTO monthly_archives (id, archived_at, data) VALUES ('2025-01', 1738400000,
+			'{"id":"2025-01","totalSpent":10,"periodStart":1735689600,"periodEnd":1738368000,"archivedAt":1738400000}')`,
+	}
+	for _, statement := range statements {
+		if _, err := legacy.ExecContext(ctx, statement); err != nil {
+			t.Fatalf("Failed to seed the legacy database: %v\n%s", err, statement)
+		}
+	}
+	legacy.Close()
+
+	store, err := NewSQLite(ctx, path)
+	if err != nil {
+		t.Fatal("Failed to migrate:", err)
+	}
+	defer store.Close(ctx)
+
+	var version int
+	if err := store.db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil || version != len(sqliteMigrations) {
+		t.Fatalf("Expected schema version %d, got %d, %v", len(sqliteMigrations), version, err)
+	}
+
+	// 2: amounts in cents, archives included
+	tx, err := store.FindTransaction(ctx, "1")
+	if err != nil || tx == nil || tx.Amount != 2550 {
+		t.Errorf("Expected 25.50 in cents, got %+v, %v", tx, err)
+	}
+	archive, err := store.GetMonthlyArchive(ctx, "2025-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archive.TotalSpent != 1299 || archive.UserTotals["bob"] != 1299 || archive.CategoryTotals["Groceries"] != 1299 ||
+		archive.Balances[0].Net != 649 || archive.Transactions[0].Amount != 1299 {
+		t.Errorf("Expected the archive's amounts in cents, got %+v", archive)
+	}
+
+	// 3: the trash
+	if err := store.DeleteTransaction(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, _ := store.GetDeletedTransactions(ctx); len(deleted) != 1 {
+		t.Errorf("Expected the deleted transaction in the trash, got %d", len(deleted))
+	}
+
+	// 4 and 5: reset snapshots and state
+	if err := store.ResetTransactions(ctx, &models.ResetSnapshot{ID: "snapshot"}); err != nil {
+		t.Error("Expected reset snapshots to be saved:", err)
+	}
+	march := models.MonthPeriod(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+	if err := store.SaveResetState(ctx, models.NewResetState(march, 1)); err != nil {
+		t.Error("Expected the reset state to be saved:", err)
+	}
+
+	// 6: leases
+	if ok, err := store.AcquireLease(ctx, "reset", "a", time.Minute); err != nil || !ok {
+		t.Errorf("Expected the lease to be taken, got %v, %v", ok, err)
+	}
+
+	// 7: archives by period, the legacy one last
+	archives, _ := store.GetAllArchives(ctx)
+	if len(archives) != 2 || archives[0].ID != "2025-01" || archives[1].ID != "2025-02" {
+		t.Errorf("Expected the archive with a period first, got %v", archiveIDs(archives))
+	}
+
+	// Reopening applies nothing twice
+	store.Close(ctx)
+	store, err = NewSQLite(ctx, path)
+	if err != nil {
+		t.Fatal("Failed to reopen:", err)
+	}
+	if archive, _ := store.GetMonthlyArchive(ctx, "2025-02"); archive == nil || archive.TotalSpent != 1299 {
+		t.Errorf("Expected amounts converted only once, got %+v", archive)
+	}
+}
+
+// failDeletes makes every delete from the transactions table fail, so a
+// store operation fails after it wrote its copy
+func failDeletes(t *testing.T, store *SQLiteDB) {
+	t.Helper()
+	_, err := store.db.Exec(`CREATE TRIGGER fail_deletes BEFORE DELETE ON transactions
+		BEGIN SELECT RAISE(ABORT, 'delete failed'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLiteArchiveRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := openSQLite(t)
+	march := models.MonthPeriod(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+	insert(t, store, models.Transaction{ID: "1", Amount: 1000, Author: "alice", CreatedAt: march.Start.Unix()})
+
+	failDeletes(t, store)
+	if _, _, err := store.ArchivePeriod(ctx, march); err == nil {
+		t.Fatal("Expected archiving to fail")
+	}
+	if archive, _ := store.GetMonthlyArchive(ctx, march.ID()); archive != nil {
+		t.Errorf("Expected no archive from a failed run, got %+v", archive)
+	}
+	if all, _ := store.GetAllTransactions(ctx); len(all) != 1 {
+		t.Errorf("Expected the transaction kept, got %d", len(all))
+	}
+}
+
+func TestSQLiteResetRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := openSQLite(t)
+	insert(t, store, models.Transaction{ID: "1", Amount: 1000, Author: "alice", CreatedAt: 100})
+
+	failDeletes(t, store)
+	if err := store.ResetTransactions(ctx, &models.ResetSnapshot{ID: "snapshot"}); err == nil {
+		t.Fatal("Expected the reset to fail")
+	}
+	if snapshot, _ := store.GetLatestResetSnapshot(ctx); snapshot != nil {
+		t.Errorf("Expected no snapshot from a failed reset, got %+v", snapshot)
+	}
+	if all, _ := store.GetAllTransactions(ctx); len(all) != 1 {
+		t.Errorf("Expected the transaction kept, got %d", len(all))
+	}
+}
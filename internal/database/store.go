@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"telegram-expense-bot/internal/config"
+	"telegram-expense-bot/internal/ledger"
+	"telegram-expense-bot/internal/models"
+)
+
+// Store is the persistence layer used by the bot handlers
+type Store interface {
+	InsertTransaction(ctx context.Context, tx *models.Transaction) error
+	FindTransaction(ctx context.Context, id string) (*models.Transaction, error)
+	UpdateTransaction(ctx context.Context, id string, update *Update) error
+	DeleteTransaction(ctx context.Context, id string) error
+	GetAllTransactions(ctx context.Context) ([]models.Transaction, error)
+	GetRecentTransactions(ctx context.Context, limit int) ([]models.Transaction, error)
+	DeleteAllTransactions(ctx context.Context) error
+
+	// Deleted transactions stay in the trash until they are restored or purged
+	GetDeletedTransactions(ctx context.Context) ([]models.Transaction, error)
+	RestoreTransaction(ctx context.Context, id string) (*models.Transaction, error)
+	PurgeDeletedTransactions(ctx context.Context, before int64) (int, error)
+
+	ResetTransactions(ctx context.Context, snapshot *models.ResetSnapshot) error
+	GetLatestResetSnapshot(ctx context.Context) (*models.ResetSnapshot, error)
+	// MarkResetRestored records that a snapshot was restored, reporting
+	// false if it already was
+	MarkResetRestored(ctx context.Context, id string, at int64) (bool, error)
+	GetResetState(ctx context.Context) (*models.ResetState, error)
+	SaveResetState(ctx context.Context, state *models.ResetState) error
+
+	// A lease is held by one run of a job across every bot process using
+	// the store, until it is released or its ttl runs out
+	AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, owner string) error
+
+	CalculateTotals(ctx context.Context) (*models.Totals, error)
+	SearchTransactions(ctx context.Context, filter *models.SearchFilter) ([]models.Transaction, error)
+
+	// ArchivePeriod returns the period's archive and how many transactions
+	// it added to it, which differs from the archive's own counts when the
+	// period was archived before
+	ArchivePeriod(ctx context.Context, period models.Period) (*models.MonthlyArchive, int, error)
+	// MoveArchive saves archive, whose ID must be free, in place of the
+	// archive fromID
+	MoveArchive(ctx context.Context, fromID string, archive *models.MonthlyArchive) error
+	GetMonthlyArchive(ctx context.Context, monthID string) (*models.MonthlyArchive, error)
+	GetRecentArchives(ctx context.Context, limit int) ([]models.MonthlyArchive, error)
+	GetAllArchives(ctx context.Context) ([]models.MonthlyArchive, error)
+
+	Close(ctx context.Context) error
+}
+
+// Open creates the store selected by the configured storage backend
+func Open(ctx context.Context, cfg *config.Config) (Store, error) {
+	switch cfg.StorageBackend {
+	case config.BackendMongo:
+		db, err := New(ctx, cfg.MongoURI, cfg.MongoDB, "transactions")
+		if err != nil {
+			return nil, err
+		}
+		db.SetMembers(cfg.Members)
+		return db, nil
+	case config.BackendSQLite:
+		db, err := NewSQLite(ctx, cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		db.SetMembers(cfg.Members)
+		return db, nil
+	case config.BackendMemory:
+		db := NewMemory()
+		db.SetMembers(cfg.Members)
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// ErrNothingToArchive is returned by ArchivePeriod when no transaction is
+// dated within the period
+var ErrNothingToArchive = errors.New("no transactions to archive")
+
+// transactionIDs lists the IDs of transactions
+func transactionIDs(transactions []models.Transaction) []string {
+	ids := make([]string, 0, len(transactions))
+	for _, tx := range transactions {
+		ids = append(ids, tx.ID)
+	}
+	return ids
+}
+
+// mergeArchived adds transactions to the ones already in a period's
+// archive, for a period archived again. A transaction in both keeps the
+// copy being archived now. An archive under the period's ID that covers
+// other dates is never merged into; it has to be moved first.
+func mergeArchived(existing *models.MonthlyArchive, transactions []models.Transaction, period models.Period) ([]models.Transaction, error) {
+	if existing == nil {
+		return transactions, nil
+	}
+	if existing.PeriodStart != period.Start.Unix() || existing.PeriodEnd != period.End.Unix() {
+		return nil, fmt.Errorf("archive %s covers other dates than %s", existing.ID, period.Label())
+	}
+	ids := make(map[string]bool, len(transactions))
+	for _, tx := range transactions {
+		ids[tx.ID] = true
+	}
+	var merged []models.Transaction
+	for _, tx := range existing.Transactions {
+		if !ids[tx.ID] {
+			merged = append(merged, tx)
+		}
+	}
+	merged = append(merged, transactions...)
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt < merged[j].CreatedAt
+	})
+	return merged, nil
+}
+
+// buildArchive creates the archive record of a period for its transactions
+func buildArchive(transactions []models.Transaction, members []string, period models.Period, now time.Time) *models.MonthlyArchive {
+	totals := ledger.Compute(transactions, members)
+
+	// Calculate additional stats over expenses only; refunds are already
+	// netted into the total and settlements just move money
+	expenses := models.Expenses(transactions)
+	totalSpent := totals.TotalSpent
+	var highestAmount models.Money
+	lowestAmount := models.Money(math.MaxInt64)
+	uniqueDays := make(map[string]bool)
+
+	for _, tx := range expenses {
+		amt := tx.HomeAmount()
+
+		if amt > highestAmount {
+			highestAmount = amt
+		}
+		if amt < lowestAmount {
+			lowestAmount = amt
+		}
+
+		day := time.Unix(tx.CreatedAt, 0).Format("2006-01-02")
+		uniqueDays[day] = true
+	}
+
+	var avgTransaction models.Money
+	if len(expenses) > 0 {
+		avgTransaction = totalSpent.Div(len(expenses))
+	} else {
+		lowestAmount = 0
+	}
+
+	return &models.MonthlyArchive{
+		ID:                 period.ID(),
+		Year:               period.Start.Year(),
+		Month:              int(period.Start.Month()),
+		MonthName:          period.Start.Format("January"),
+		Label:              period.Label(),
+		TotalSpent:         totalSpent,
+		TotalTransactions:  len(expenses),
+		TotalRefunded:      totals.TotalRefunded,
+		TotalIncome:        totals.TotalIncome,
+		TotalSettled:       totals.TotalSettled,
+		Balance:            totals.Outstanding,
+		Balances:           totals.Balances,
+		UserTotals:         totals.UserTotals,
+		CategoryTotals:     totals.CategoryTotals,
+		Transactions:       transactions,
+		AvgTransaction:     avgTransaction,
+		HighestTransaction: highestAmount,
+		LowestTransaction:  lowestAmount,
+		DaysWithSpending:   len(uniqueDays),
+		PeriodStart:        period.Start.Unix(),
+		PeriodEnd:          period.End.Unix(),
+		ArchivedAt:         now.Unix(),
+	}
+}
+
+// searchTransactions returns the live and archived transactions that match
+// filter, newest first. An archived copy of a live transaction, left by a
+// reset that failed to clear it, is skipped.
+func searchTransactions(live []models.Transaction, archives []models.MonthlyArchive, filter *models.SearchFilter) []models.Transaction {
+	var found []models.Transaction
+	seen := make(map[string]bool, len(live))
+	for _, tx := range live {
+		seen[tx.ID] = true
+		if filter.Matches(&tx) {
+			found = append(found, tx)
+		}
+	}
+	for _, archive := range archives {
+		for _, tx := range archive.Transactions {
+			if !seen[tx.ID] && filter.Matches(&tx) {
+				found = append(found, tx)
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].CreatedAt > found[j].CreatedAt
+	})
+	return found
+}
+
+// sortDeleted orders transactions in the trash, most recently deleted first
+func sortDeleted(deleted []models.Transaction) {
+	sort.SliceStable(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt > deleted[j].DeletedAt
+	})
+}
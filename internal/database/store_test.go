@@ -0,0 +1,218 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+)
+
+var testMembers = []string{"alice", "bob"}
+
+// testStores runs a test against every backend that runs without a
+// server: the in-memory store and SQLite in memory
+func testStores(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		store := NewMemory()
+		store.SetMembers(testMembers)
+		test(t, store)
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, openSQLite(t))
+	})
+}
+
+// insert adds transactions to store, failing the test on an error
+func insert(t *testing.T, store Store, transactions ...models.Transaction) {
+	t.Helper()
+	for i := range transactions {
+		if err := store.InsertTransaction(context.Background(), &transactions[i]); err != nil {
+			t.Fatal("Failed to insert transaction:", err)
+		}
+	}
+}
+
+func TestStoreTransactions(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		base := time.Date(2025, time.March, 14, 9, 0, 0, 0, time.UTC).Unix()
+
+		insert(t, store,
+			models.Transaction{ID: "1", Amount: 3000, Author: "alice", Category: "Groceries", CreatedAt: base},
+			models.Transaction{ID: "2", Amount: 1000, Author: "bob", Category: "Transport", CreatedAt: base + 60},
+			models.Transaction{ID: "3", Kind: models.KindSettlement, Amount: 500, Author: "bob", To: "alice", CreatedAt: base + 30},
+		)
+		if err := store.InsertTransaction(ctx, &models.Transaction{ID: "1", Amount: 1}); err == nil {
+			t.Error("Expected a second transaction with a live ID to be refused")
+		}
+
+		tx, err := store.FindTransaction(ctx, "2")
+		if err != nil || tx == nil || tx.Amount != 1000 || tx.Author != "bob" {
+			t.Fatalf("Expected transaction 2, got %+v, %v", tx, err)
+		}
+		if tx, err := store.FindTransaction(ctx, "missing"); err != nil || tx != nil {
+			t.Errorf("Expected no transaction for an unknown ID, got %+v, %v", tx, err)
+		}
+
+		// Only the fields set change
+		update := new(Update).SetAmount(1250, "10+2.50").SetNote("bus")
+		if err := store.UpdateTransaction(ctx, "2", update); err != nil {
+			t.Fatal(err)
+		}
+		tx, _ = store.FindTransaction(ctx, "2")
+		if tx.Amount != 1250 || tx.Expression != "10+2.50" || tx.Note != "bus" || tx.Category != "Transport" {
+			t.Errorf("Expected the amount and note changed and the rest kept, got %+v", tx)
+		}
+
+		if all, _ := store.GetAllTransactions(ctx); len(all) != 3 {
+			t.Errorf("Expected every transaction, got %v", transactionIDs(all))
+		}
+		recent, _ := store.GetRecentTransactions(ctx, 2)
+		if got := transactionIDs(recent); len(got) != 2 || got[0] != "2" || got[1] != "3" {
+			t.Errorf("Expected the two newest, newest first, got %v", got)
+		}
+
+		// alice paid 30.00 and bob 12.50 for both, and bob paid alice 5.00
+		totals, err := store.CalculateTotals(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if totals.TotalSpent != 4250 || totals.TotalSettled != 500 {
+			t.Errorf("Expected 42.50 spent and 5.00 settled, got %s and %s", totals.TotalSpent, totals.TotalSettled)
+		}
+		for _, b := range totals.Balances {
+			if want := map[string]models.Money{"alice": 375, "bob": -375}[b.User]; b.Net != want {
+				t.Errorf("Expected %s's net to be %s, got %s", b.User, want, b.Net)
+			}
+		}
+	})
+}
+
+func TestStoreTrash(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		retention := 30 * 24 * time.Hour
+		expired := time.Now().Add(-retention - time.Hour).Unix()
+
+		insert(t, store,
+			models.Transaction{ID: "old", Amount: 900, Author: "bob", CreatedAt: 50, DeletedAt: expired},
+			models.Transaction{ID: "1", Amount: 3000, Author: "alice", CreatedAt: 100},
+			models.Transaction{ID: "2", Amount: 1000, Author: "bob", CreatedAt: 200},
+			models.Transaction{ID: "3", Amount: 500, Author: "bob", CreatedAt: 300},
+		)
+		for _, id := range []string{"1", "2", "2"} {
+			if err := store.DeleteTransaction(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Deleted transactions leave the live set for the trash
+		if tx, _ := store.FindTransaction(ctx, "1"); tx != nil {
+			t.Errorf("Expected transaction 1 to be gone, got %+v", tx)
+		}
+		if all, _ := store.GetAllTransactions(ctx); len(all) != 1 || all[0].ID != "3" {
+			t.Errorf("Expected only transaction 3 live, got %v", transactionIDs(all))
+		}
+		if totals, _ := store.CalculateTotals(ctx); totals == nil || totals.TotalSpent != 500 {
+			t.Errorf("Expected only 5.00 spent, got %+v", totals)
+		}
+		deleted, err := store.GetDeletedTransactions(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := transactionIDs(deleted); !reflect.DeepEqual(got, []string{"2", "1", "old"}) {
+			t.Errorf("Expected the trash most recently deleted first, got %v", got)
+		}
+
+		tx, err := store.RestoreTransaction(ctx, "1")
+		if err != nil || tx == nil || tx.Amount != 3000 || tx.DeletedAt != 0 {
+			t.Fatalf("Expected transaction 1 restored, got %+v, %v", tx, err)
+		}
+		if tx, _ := store.FindTransaction(ctx, "1"); tx == nil {
+			t.Error("Expected transaction 1 to be live again")
+		}
+		for _, id := range []string{"1", "3", "missing"} {
+			if tx, err := store.RestoreTransaction(ctx, id); err != nil || tx != nil {
+				t.Errorf("Expected nothing to restore for %s, got %+v, %v", id, tx, err)
+			}
+		}
+
+		// A new transaction with a trashed ID, e.g. a reinserted message,
+		// replaces the trashed one
+		insert(t, store, models.Transaction{ID: "2", Amount: 1500, Author: "alice", CreatedAt: 400})
+		if tx, _ := store.FindTransaction(ctx, "2"); tx == nil || tx.Amount != 1500 || tx.Author != "alice" {
+			t.Errorf("Expected the new transaction 2, got %+v", tx)
+		}
+
+		// Only transactions deleted before the retention period are purged
+		if err := store.DeleteTransaction(ctx, "3"); err != nil {
+			t.Fatal(err)
+		}
+		purged, err := store.PurgeDeletedTransactions(ctx, time.Now().Add(-retention).Unix())
+		if err != nil || purged != 1 {
+			t.Errorf("Expected 1 transaction purged, got %d, %v", purged, err)
+		}
+		deleted, _ = store.GetDeletedTransactions(ctx)
+		if got := transactionIDs(deleted); !reflect.DeepEqual(got, []string{"3"}) {
+			t.Errorf("Expected only transaction 3 left in the trash, got %v", got)
+		}
+	})
+}
+
+func TestStoreResets(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		insert(t, store,
+			models.Transaction{ID: "1", Amount: 3000, Author: "alice", CreatedAt: 100},
+			models.Transaction{ID: "2", Amount: 1000, Author: "bob", CreatedAt: 200},
+		)
+		if err := store.DeleteTransaction(ctx, "2"); err != nil {
+			t.Fatal(err)
+		}
+
+		// The snapshot holds the trash too, and nothing is left behind
+		snapshot := &models.ResetSnapshot{ID: "20250314-090000-abcd", By: "alice", CreatedAt: 300}
+		if err := store.ResetTransactions(ctx, snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if got := transactionIDs(snapshot.Transactions); len(got) != 2 {
+			t.Errorf("Expected both transactions in the snapshot, got %v", got)
+		}
+		if all, _ := store.GetAllTransactions(ctx); len(all) != 0 {
+			t.Errorf("Expected no transactions after the reset, got %v", transactionIDs(all))
+		}
+		if deleted, _ := store.GetDeletedTransactions(ctx); len(deleted) != 0 {
+			t.Errorf("Expected an empty trash after the reset, got %v", transactionIDs(deleted))
+		}
+
+		latest, err := store.GetLatestResetSnapshot(ctx)
+		if err != nil || latest == nil || latest.ID != snapshot.ID || len(latest.Transactions) != 2 {
+			t.Fatalf("Expected the snapshot back, got %+v, %v", latest, err)
+		}
+		if ok, err := store.MarkResetRestored(ctx, snapshot.ID, 400); err != nil || !ok {
+			t.Errorf("Expected the first restore to be recorded, got %v, %v", ok, err)
+		}
+		if ok, err := store.MarkResetRestored(ctx, snapshot.ID, 500); err != nil || ok {
+			t.Errorf("Expected a second restore to be refused, got %v, %v", ok, err)
+		}
+		if latest, _ := store.GetLatestResetSnapshot(ctx); latest == nil || latest.RestoredAt != 400 {
+			t.Errorf("Expected the snapshot restored at 400, got %+v", latest)
+		}
+
+		if state, err := store.GetResetState(ctx); err != nil || state != nil {
+			t.Errorf("Expected no reset state yet, got %+v, %v", state, err)
+		}
+		march := models.MonthPeriod(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+		if err := store.SaveResetState(ctx, models.NewResetState(march, 600)); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SaveResetState(ctx, models.NewResetState(models.MonthPeriod(march.End), 700)); err != nil {
+			t.Fatal(err)
+		}
+		if state, _ := store.GetResetState(ctx); state == nil || state.PeriodStart != march.End.Unix() || state.CompletedAt != 700 {
+			t.Errorf("Expected the latest reset state, got %+v", state)
+		}
+	})
+}
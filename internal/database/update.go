@@ -0,0 +1,96 @@
+package database
+
+import "telegram-expense-bot/internal/models"
+
+// Update is a change to some fields of a stored transaction, built with its
+// Set methods. Fields that aren't set keep their stored value; setting a
+// field to its zero value clears it.
+type Update struct {
+	changes []change
+}
+
+// change sets one stored field, named as in the stored document
+type change struct {
+	field string
+	value interface{}
+	apply func(tx *models.Transaction)
+}
+
+// set records a field's new value and how to make the change to a
+// transaction
+func (u *Update) set(field string, value interface{}, apply func(tx *models.Transaction)) *Update {
+	u.changes = append(u.changes, change{field: field, value: value, apply: apply})
+	return u
+}
+
+// Merge adds the changes of other to u
+func (u *Update) Merge(other *Update) *Update {
+	u.changes = append(u.changes, other.changes...)
+	return u
+}
+
+// applyTo makes the changes to tx, for backends that store the transaction
+// as a whole document
+func (u *Update) applyTo(tx *models.Transaction) {
+	for _, c := range u.changes {
+		c.apply(tx)
+	}
+}
+
+// SetKind changes what kind of transaction it is, e.g. a refund
+func (u *Update) SetKind(kind string) *Update {
+	return u.set("kind", kind, func(tx *models.Transaction) { tx.Kind = kind })
+}
+
+// SetAmount changes the amount and what it was typed as
+func (u *Update) SetAmount(amount models.Money, expression string) *Update {
+	u.set("expression", expression, func(tx *models.Transaction) { tx.Expression = expression })
+	return u.set("amount", amount, func(tx *models.Transaction) { tx.Amount = amount })
+}
+
+// SetCurrency changes the currency of the amount and its rate to the home
+// currency
+func (u *Update) SetCurrency(currency string, rate float64) *Update {
+	u.set("rate", rate, func(tx *models.Transaction) { tx.Rate = rate })
+	return u.set("currency", currency, func(tx *models.Transaction) { tx.Currency = currency })
+}
+
+// SetPayer changes who paid; empty means the author
+func (u *Update) SetPayer(payer string) *Update {
+	return u.set("payer", payer, func(tx *models.Transaction) { tx.Payer = payer })
+}
+
+// SetBeneficiaries changes who the transaction was for
+func (u *Update) SetBeneficiaries(beneficiaries []string) *Update {
+	return u.set("beneficiaries", beneficiaries, func(tx *models.Transaction) { tx.Beneficiaries = beneficiaries })
+}
+
+// SetTo changes the recipient of a settlement
+func (u *Update) SetTo(to string) *Update {
+	return u.set("to", to, func(tx *models.Transaction) { tx.To = to })
+}
+
+// SetCategory changes the category
+func (u *Update) SetCategory(category string) *Update {
+	return u.set("category", category, func(tx *models.Transaction) { tx.Category = category })
+}
+
+// SetNote changes the note
+func (u *Update) SetNote(note string) *Update {
+	return u.set("note", note, func(tx *models.Transaction) { tx.Note = note })
+}
+
+// SetSplit changes how the amount is split; nil splits it equally
+func (u *Update) SetSplit(split *models.Split) *Update {
+	return u.set("split", split, func(tx *models.Transaction) { tx.Split = split })
+}
+
+// SetButtonMessageID changes the bot message that shows the transaction
+func (u *Update) SetButtonMessageID(id string) *Update {
+	return u.set("buttonMessageId", id, func(tx *models.Transaction) { tx.ButtonMessageID = id })
+}
+
+// SetCreatedAt changes the date of the transaction
+func (u *Update) SetCreatedAt(createdAt int64) *Update {
+	return u.set("createdAt", createdAt, func(tx *models.Transaction) { tx.CreatedAt = createdAt })
+}
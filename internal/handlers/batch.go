@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"telegram-expense-bot/internal/database"
+	"telegram-expense-bot/internal/models"
+	"telegram-expense-bot/internal/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxBatchLines caps the lines read from one message, which keeps the
+// summary keyboard within Telegram's button limit
+const maxBatchLines = 12
+
+// messageLines returns the non-blank lines of a message
+func messageLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// batchTransactionID derives the ID of one line's transaction from the
+// message ID, e.g. "512-3" for the third line of message 512
+func batchTransactionID(sourceID string, line int) string {
+	return fmt.Sprintf("%s-%d", sourceID, line)
+}
+
+// parseBatchLine parses one line of a message with several transactions.
+// ok is false for lines that are not a transaction; err reports a
+// transaction that mentions someone who is not a member.
+func (h *EventHandler) parseBatchLine(message *tgbotapi.Message, line string) (*utils.ParsedTransaction, bool, error) {
+	parsed, err := utils.ParseTransaction(line, h.parseOptions(message))
+	if err != nil {
+		return nil, false, nil
+	}
+	if err := h.resolveMentions(parsed); err != nil {
+		return nil, true, err
+	}
+	if parsed.Kind == models.KindSettlement && strings.EqualFold(parsed.To, message.From.UserName) {
+		return nil, false, nil
+	}
+	return parsed, true, nil
+}
+
+// handleNewBatch records one transaction per line of a message and sends
+// a single summary with category buttons for each line. Lines that are
+// not transactions, like a "Costco run:" heading, are skipped.
+func (h *EventHandler) handleNewBatch(bot *tgbotapi.BotAPI, message *tgbotapi.Message, lines []string) {
+	ctx := context.Background()
+	sourceID := strconv.Itoa(message.MessageID)
+
+	var added []models.Transaction
+	var problems []string
+	for i, line := range lines {
+		parsed, ok, err := h.parseBatchLine(message, line)
+		if !ok {
+			continue
+		}
+		if i >= maxBatchLines {
+			problems = append(problems, fmt.Sprintf("Line %d: only the first %d lines are read", i+1, maxBatchLines))
+			break
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Line %d: %v", i+1, err))
+			continue
+		}
+
+		tx, err := h.newTransaction(ctx, bot, message, batchTransactionID(sourceID, i+1), parsed)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Line %d: %v", i+1, err))
+			continue
+		}
+		tx.SourceMessageID, tx.Line = sourceID, i+1
+		if err := h.db.InsertTransaction(ctx, tx); err != nil {
+			log.Println("Failed to insert transaction:", err)
+			problems = append(problems, fmt.Sprintf("Line %d: failed to save in DB", i+1))
+			continue
+		}
+		added = append(added, *tx)
+	}
+	h.sendBatchProblems(bot, message.Chat.ID, problems)
+	if len(added) == 0 {
+		return
+	}
+	h.sendBatchMessage(bot, message.Chat.ID, added)
+}
+
+// sendBatchMessage sends the summary of the transactions of one message
+// and stores it as their button message
+func (h *EventHandler) sendBatchMessage(bot *tgbotapi.BotAPI, chatID int64, batch []models.Transaction) {
+	content, keyboard := h.batchMessage(batch)
+	msg := tgbotapi.NewMessage(chatID, content)
+	msg.ReplyMarkup = keyboard
+
+	sentMsg, err := bot.Send(msg)
+	if err != nil {
+		log.Println("Failed to send batch summary:", err)
+		return
+	}
+
+	// Every line shares the summary as its button message
+	ctx := context.Background()
+	buttonMsgID := strconv.Itoa(sentMsg.MessageID)
+	for _, tx := range batch {
+		err = h.db.UpdateTransaction(ctx, tx.ID, new(database.Update).SetButtonMessageID(buttonMsgID))
+		if err != nil {
+			log.Println("Failed to update buttonMessageId in DB:", err)
+		}
+	}
+}
+
+// handleEditedBatch applies an edit to a message with several
+// transactions: each line updates the transaction at the same position,
+// new lines add transactions and lines that were removed or no longer
+// parse delete theirs
+func (h *EventHandler) handleEditedBatch(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	ctx := context.Background()
+	sourceID := strconv.Itoa(message.MessageID)
+
+	existing := h.batchTransactions(ctx, sourceID)
+	if len(existing) == 0 {
+		return
+	}
+	buttonMsgID := existing[0].ButtonMessageID
+	byLine := make(map[int]*models.Transaction, len(existing))
+	for i := range existing {
+		byLine[existing[i].Line] = &existing[i]
+	}
+
+	var problems []string
+	for i, line := range messageLines(message.Text) {
+		if i >= maxBatchLines {
+			break
+		}
+		n := i + 1
+		tx := byLine[n]
+		delete(byLine, n)
+
+		parsed, ok, err := h.parseBatchLine(message, line)
+		if err != nil {
+			// The line keeps its transaction as it was
+			problems = append(problems, fmt.Sprintf("Line %d: %v", n, err))
+			continue
+		}
+		switch {
+		case !ok && tx != nil:
+			byLine[n] = tx // Deleted below
+
+		case ok && tx != nil:
+			update, err := h.editTransaction(ctx, bot, message.Chat.ID, tx, parsed)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("Line %d: %v", n, err))
+				continue
+			}
+			if err := h.db.UpdateTransaction(ctx, tx.ID, update); err != nil {
+				log.Println("Failed to update transaction amount:", err)
+			}
+
+		case ok:
+			tx, err := h.newTransaction(ctx, bot, message, batchTransactionID(sourceID, n), parsed)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("Line %d: %v", n, err))
+				continue
+			}
+			tx.SourceMessageID, tx.Line, tx.ButtonMessageID = sourceID, n, buttonMsgID
+			if err := h.db.InsertTransaction(ctx, tx); err != nil {
+				log.Println("Failed to insert transaction:", err)
+			}
+		}
+	}
+
+	for _, tx := range byLine {
+		if err := h.db.DeleteTransaction(ctx, tx.ID); err != nil {
+			log.Println("Failed to delete transaction from DB:", err)
+		}
+	}
+	h.sendBatchProblems(bot, message.Chat.ID, problems)
+
+	if buttonMsgID != "" {
+		summaryID, _ := strconv.Atoi(buttonMsgID)
+		h.updateBatchMessage(bot, message.Chat.ID, summaryID, sourceID)
+	}
+}
+
+// sendBatchProblems reports the lines of a message that were not recorded
+func (h *EventHandler) sendBatchProblems(bot *tgbotapi.BotAPI, chatID int64, problems []string) {
+	if len(problems) == 0 {
+		return
+	}
+	content := "⚠️ Some lines were not added:\n" + strings.Join(problems, "\n")
+	msg := tgbotapi.NewMessage(chatID, content)
+	bot.Send(msg)
+}
+
+// batchTransactions returns the transactions recorded from one message,
+// in line order
+func (h *EventHandler) batchTransactions(ctx context.Context, sourceID string) []models.Transaction {
+	transactions, err := h.db.GetAllTransactions(ctx)
+	if err != nil {
+		log.Println("Failed to fetch transactions:", err)
+		return nil
+	}
+
+	var batch []models.Transaction
+	for _, tx := range transactions {
+		if tx.SourceMessageID == sourceID {
+			batch = append(batch, tx)
+		}
+	}
+	sort.Slice(batch, func(i, j int) bool {
+		return batch[i].Line < batch[j].Line
+	})
+	return batch
+}
+
+// updateBatchMessage redraws the summary of a message with several
+// transactions, deleting it once none are left
+func (h *EventHandler) updateBatchMessage(bot *tgbotapi.BotAPI, chatID int64, messageID int, sourceID string) {
+	batch := h.batchTransactions(context.Background(), sourceID)
+	if len(batch) == 0 {
+		deleteMsg := tgbotapi.NewDeleteMessage(chatID, messageID)
+		bot.Request(deleteMsg)
+		return
+	}
+
+	content, keyboard := h.batchMessage(batch)
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, content)
+	editMsg.ReplyMarkup = &keyboard
+
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to update batch summary:", err)
+	}
+}
+
+// batchMessage builds the summary text and keyboard for the transactions
+// of one message
+func (h *EventHandler) batchMessage(batch []models.Transaction) (string, tgbotapi.InlineKeyboardMarkup) {
+	var total models.Money
+	var lines []string
+	for i := range batch {
+		tx := &batch[i]
+		amount := utils.FormatTransactionAmount(tx, h.config.HomeCurrency)
+
+		var line string
+		switch {
+		case tx.IsSettlement():
+			line = fmt.Sprintf("💸 %s paid %s %s", tx.PaidBy(), tx.To, amount)
+		case tx.Kind == models.KindIncome:
+			line = fmt.Sprintf("💰 %s income received by %s", amount, tx.PaidBy())
+		default:
+			category := tx.Category
+			if category == "" {
+				category = "❔ no category yet"
+			}
+			if tx.Kind == models.KindRefund {
+				amount = "refund of " + amount
+				total -= tx.HomeAmount()
+			} else {
+				total += tx.HomeAmount()
+			}
+			line = fmt.Sprintf("%s, %s", amount, category)
+		}
+		if tx.PaidBy() != tx.Author && !tx.IsSettlement() {
+			line += fmt.Sprintf(", paid by %s", tx.PaidBy())
+		}
+		if tx.Note != "" {
+			line += fmt.Sprintf(" (%s)", tx.Note)
+		}
+		lines = append(lines, fmt.Sprintf("%d. %s", tx.Line, line))
+	}
+
+	content := fmt.Sprintf("🧾 Added %s:\n%s", plural(len(batch), "transaction"), strings.Join(lines, "\n"))
+	if total != 0 {
+		content += fmt.Sprintf("\n\n💵 Total spent: %s", utils.FormatHome(total, h.config.HomeCurrency))
+	}
+	for _, tx := range batch {
+		if tx.HasCategory() {
+			content += "\n\nTap a line's category to change it:"
+			break
+		}
+	}
+	return content, utils.BuildBatchKeyboard(h.config.Categories, batch)
+}
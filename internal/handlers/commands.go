@@ -3,72 +3,76 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"math"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"telegram-expense-bot/internal/config"
 	"telegram-expense-bot/internal/database"
+	"telegram-expense-bot/internal/ledger"
 	"telegram-expense-bot/internal/models"
 	"telegram-expense-bot/internal/utils"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/robfig/cron/v3"
 )
 
 // CommandHandler handles bot commands
 type CommandHandler struct {
-	db     *database.DB
+	db     database.Store
 	config *config.Config
+
+	resetsMu sync.Mutex
+	resets   map[int]time.Time // Pending /reset requests by message ID, with their expiry
 }
 
 // NewCommandHandler creates a new command handler
-func NewCommandHandler(db *database.DB, config *config.Config) *CommandHandler {
+func NewCommandHandler(db database.Store, config *config.Config) *CommandHandler {
 	return &CommandHandler{
 		db:     db,
 		config: config,
+		resets: make(map[int]time.Time),
 	}
 }
 
 // SendTotals sends current transaction totals
 func (h *CommandHandler) SendTotals(bot *tgbotapi.BotAPI, chatID int64) {
 	ctx := context.Background()
-	balance, categoryTotals, userTotals, err := h.db.CalculateTotals(ctx)
+	totals, err := h.db.CalculateTotals(ctx)
 	if err != nil {
 		log.Println("Failed to calculate totals:", err)
 		msg := tgbotapi.NewMessage(chatID, "Error calculating totals.")
 		bot.Send(msg)
 		return
 	}
+	categoryTotals := totals.CategoryTotals
 
-	// Get additional analytics
-	transactions, _ := h.db.GetAllTransactions(ctx)
+	// Get additional analytics (settlements are not spending)
+	allTransactions, _ := h.db.GetAllTransactions(ctx)
+	transactions := models.Expenses(allTransactions)
 	
 	var totalsText string
 	totalsText += "📊 **EXPENSE SUMMARY**\n"
 	totalsText += "════════════\n\n"
 
 	// Balance section
-	var users []string
-	for user := range userTotals {
-		users = append(users, user)
-	}
-
-	if len(users) >= 2 {
+	if len(totals.UserTotals) > 0 {
 		totalsText += "💰 **Balance:**\n"
-		if balance > 0 {
-			totalsText += fmt.Sprintf("   %s owes **%.2f$** to %s\n\n", users[1], math.Abs(balance), users[0])
-		} else if balance < 0 {
-			totalsText += fmt.Sprintf("   %s owes **%.2f$** to %s\n\n", users[0], math.Abs(balance), users[1])
-		} else {
-			totalsText += "   ✅ All settled! (0$)\n\n"
-		}
+		totalsText += h.formatBalances(totals.Balances, fmt.Sprintf("   ✅ All settled! (%s)\n", h.money(0)))
+		totalsText += "\n"
 
 		// User contributions
 		totalsText += "👥 **User Contributions:**\n"
-		for user, amount := range userTotals {
-			totalsText += fmt.Sprintf("   %s: %.2f$\n", user, amount)
+		for _, b := range totals.Balances {
+			totalsText += fmt.Sprintf("   %s: contributed %s, share %s\n", b.User, h.money(b.Paid), h.money(b.Share))
 		}
 		totalsText += "\n"
 	} else {
@@ -77,7 +81,7 @@ func (h *CommandHandler) SendTotals(bot *tgbotapi.BotAPI, chatID int64) {
 
 	// Category breakdown with percentages and analysis
 	if len(categoryTotals) > 0 {
-		totalSpent := 0.0
+		var totalSpent models.Money
 		for _, amt := range categoryTotals {
 			totalSpent += amt
 		}
@@ -87,13 +91,13 @@ func (h *CommandHandler) SendTotals(bot *tgbotapi.BotAPI, chatID int64) {
 		// Sort categories by amount (highest first)
 		type CategoryData struct {
 			Name   string
-			Amount float64
+			Amount models.Money
 			Percent float64
 		}
 		
 		var categories []CategoryData
 		for cat, amt := range categoryTotals {
-			percent := (amt / totalSpent) * 100
+			percent := (amt.Float() / totalSpent.Float()) * 100
 			categories = append(categories, CategoryData{cat, amt, percent})
 		}
 		
@@ -122,24 +126,34 @@ func (h *CommandHandler) SendTotals(bot *tgbotapi.BotAPI, chatID int64) {
 				}
 			}
 			
-			totalsText += fmt.Sprintf("   %s **%.2f$** (%.1f%%)\n   %s\n", 
-				cat.Name, cat.Amount, cat.Percent, barGraph)
+			totalsText += fmt.Sprintf("   %s **%s** (%.1f%%)\n   %s\n", 
+				cat.Name, h.money(cat.Amount), cat.Percent, barGraph)
 		}
 		
-		totalsText += fmt.Sprintf("\n💵 **TOTAL SPENT: %.2f$**\n\n", totalSpent)
+		totalsText += fmt.Sprintf("\n💵 **TOTAL SPENT: %s**\n", h.money(totalSpent))
+		if totals.TotalRefunded > 0 {
+			totalsText += fmt.Sprintf("↩️ Refunds: %s (already deducted)\n", h.money(totals.TotalRefunded))
+		}
+		if totals.TotalIncome > 0 {
+			totalsText += fmt.Sprintf("💰 Shared income: %s\n", h.money(totals.TotalIncome))
+		}
+		if totals.TotalSettled > 0 {
+			totalsText += fmt.Sprintf("💸 Paid back this %s: %s\n", h.config.Cycle.Noun(), h.money(totals.TotalSettled))
+		}
+		totalsText += "\n"
 
 		// Analytics
 		if len(transactions) > 0 {
-			avgTransaction := totalSpent / float64(len(transactions))
+			avgTransaction := totalSpent.Div(len(transactions))
 			totalsText += "📊 **Analytics:**\n"
 			totalsText += fmt.Sprintf("   • Total transactions: %d\n", len(transactions))
-			totalsText += fmt.Sprintf("   • Average per transaction: %.2f$\n", avgTransaction)
+			totalsText += fmt.Sprintf("   • Average per transaction: %s\n", h.money(avgTransaction))
 			
 			// Find highest and lowest transaction
-			highestAmount := 0.0
-			lowestAmount := math.MaxFloat64
+			var highestAmount models.Money
+			lowestAmount := models.Money(math.MaxInt64)
 			for _, tx := range transactions {
-				amt := math.Abs(tx.Amount)
+				amt := tx.HomeAmount()
 				if amt > highestAmount {
 					highestAmount = amt
 				}
@@ -148,8 +162,8 @@ func (h *CommandHandler) SendTotals(bot *tgbotapi.BotAPI, chatID int64) {
 				}
 			}
 			
-			totalsText += fmt.Sprintf("   • Highest transaction: %.2f$\n", highestAmount)
-			totalsText += fmt.Sprintf("   • Lowest transaction: %.2f$\n", lowestAmount)
+			totalsText += fmt.Sprintf("   • Highest transaction: %s\n", h.money(highestAmount))
+			totalsText += fmt.Sprintf("   • Lowest transaction: %s\n", h.money(lowestAmount))
 			
 			// Most used category
 			if len(categories) > 0 {
@@ -158,6 +172,9 @@ func (h *CommandHandler) SendTotals(bot *tgbotapi.BotAPI, chatID int64) {
 		}
 	}
 
+	if len(ledger.Simplify(totals.Balances)) > 0 {
+		totalsText += "\n💸 Use /settle to see how to settle up"
+	}
 	totalsText += "\n🔄 Use /history to see all transactions"
 
 	msg := tgbotapi.NewMessage(chatID, totalsText)
@@ -165,43 +182,121 @@ func (h *CommandHandler) SendTotals(bot *tgbotapi.BotAPI, chatID int64) {
 	bot.Send(msg)
 }
 
-// ResetDatabase resets all transactions
-func (h *CommandHandler) ResetDatabase(bot *tgbotapi.BotAPI, chatID int64) {
+// formatBalances renders who owes whom. Two members get a single "X owes Y"
+// line; larger groups list every member's net position.
+func (h *CommandHandler) formatBalances(balances []models.MemberBalance, settledText string) string {
+	settled := true
+	for _, b := range balances {
+		if b.Net != 0 {
+			settled = false
+			break
+		}
+	}
+	if settled {
+		return settledText
+	}
+
+	// Balances are sorted by net, so the creditor comes first
+	if len(balances) == 2 {
+		return fmt.Sprintf("   %s owes **%s** to %s\n", balances[1].User, h.money(balances[1].Net.Abs()), balances[0].User)
+	}
+
+	var text string
+	for _, b := range balances {
+		if b.Net > 0 {
+			text += fmt.Sprintf("   %s is owed **%s**\n", b.User, h.money(b.Net))
+		} else if b.Net < 0 {
+			text += fmt.Sprintf("   %s owes **%s**\n", b.User, h.money(b.Net.Abs()))
+		} else {
+			text += fmt.Sprintf("   %s is settled up\n", b.User)
+		}
+	}
+	return text
+}
+
+// SendSettleUp suggests the fewest payments that settle every balance
+func (h *CommandHandler) SendSettleUp(bot *tgbotapi.BotAPI, chatID int64) {
 	ctx := context.Background()
-	err := h.db.DeleteAllTransactions(ctx)
+	totals, err := h.db.CalculateTotals(ctx)
 	if err != nil {
-		log.Println("Failed to reset database:", err)
-		msg := tgbotapi.NewMessage(chatID, "Failed to reset DB.")
+		log.Println("Failed to calculate totals for settle up:", err)
+		msg := tgbotapi.NewMessage(chatID, "Error calculating balances.")
 		bot.Send(msg)
-	} else {
-		msg := tgbotapi.NewMessage(chatID, "All transactions deleted. Fresh start!")
+		return
+	}
+
+	transfers := ledger.Simplify(totals.Balances)
+	if len(transfers) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "✅ All settled! Nobody owes anything.")
 		bot.Send(msg)
+		return
 	}
+
+	msg := tgbotapi.NewMessage(chatID, h.settleUpText(transfers))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = utils.BuildSettleKeyboard(transfers, h.config.HomeCurrency)
+	bot.Send(msg)
+}
+
+// settleUpText lists the suggested settle-up payments
+func (h *CommandHandler) settleUpText(transfers []models.Transfer) string {
+	text := "💸 **SETTLE UP**\n"
+	text += "════════════\n\n"
+	for i, t := range transfers {
+		text += fmt.Sprintf("%d. %s pays **%s** to %s\n", i+1, t.From, h.money(t.Amount), t.To)
+	}
+	text += "\nTap a payment below once it has been made to record it."
+	return text
 }
 
 // SendHelp sends help information
 func (h *CommandHandler) SendHelp(bot *tgbotapi.BotAPI, chatID int64) {
-	helpText := `**📊 Expense Tracker Bot**
+	cycle := h.config.Cycle
+	previous := cycle.Previous(h.currentPeriod())
+	helpText := fmt.Sprintf(`**📊 Expense Tracker Bot**
 
 **🏠 Basic Commands:**
-• /totals - Show current month summary
-• /history - Show all transactions
+• /totals - Show current %[1]s summary
+• /history - Show transactions, 10 per page
+• /history groceries, @user, last 20 or %[3]s - Filter the history
+• /settle - Suggest payments that settle all debts
 • /help - Show this help
 
 **📈 Analytics & Comparison:**
-• /compare - Compare recent months
+• /compare - Compare recent %[1]ss
 • /trends - Analyze spending trends
 • /export - Export CSV data
 • /export compare - Export comparison CSV
-• /export 2025-01 - Export specific month
+• /export %[3]s - Export a specific %[1]s
+
+**🔎 Search:**
+• /search costco since january - Notes, current and archived %[1]ss
+• /search 85 or >50 or 20-40 - By amount
+• /search groceries @alice march - By category, member and date
 
 **🔧 Management:**
-• /reset - Reset all transactions ⚠️
+• /delete - Reply to an expense to delete it (/delete 3 for one line)
+• /trash - Restore recently deleted transactions
+• /reset - Reset all transactions, admins only ⚠️
+• /restore - Bring back the transactions of the last reset
 
 **💰 Adding Transactions:**
 • Send a number (e.g., 25.50) to add expense
+• 25.50 groceries costco - With a category and a note
+• 12,99 dining pizza yesterday - With a date (today, yesterday, a weekday or 2025-03-14)
+• 12.50+8.99+3 or 100/3 - Add up a receipt or split a gift
+• One amount per line - Add several expenses from one message
+• 25 @user paid - Someone else paid
+• 40 for @alice @bob - Only shared by some members
+• 25 EUR or €25 - Another currency (add "at 1.47" for a manual rate)
+• refund 15 - Money back for a shared expense
+• income 200 - Shared money you received
+• paid @user 120 - Record paying someone back
+• Reply to the bot's message with text to set a note
 • Edit your message to update the amount
-• Use 🗑️ Delete button to remove transactions
+• Use ✏️ Edit to change any field of a transaction
+• Reply to an expense with /cat dining, /note text, /split 70/30, /date yesterday or /del
+• Use 🗑️ Delete button to remove transactions, then ↩️ Undo to bring one back
 
 **🗂️ Categories:**
 Groceries, Household, Entertainment, LCBO, Dining Out, Other
@@ -209,138 +304,569 @@ Groceries, Household, Entertainment, LCBO, Dining Out, Other
 **💡 How it works:**
 1. Send any number as a message
 2. Choose a category from the buttons
-3. The amount is split 50/50 between users
-4. Monthly data is automatically archived
+3. The amount is split equally between members, unless you pick Only me / Only them or the category has its own split
+4. %[2]s data is automatically archived
 5. CSV exports are sent to chat history
 
-**📊 Monthly Process:**
-• Expenses tracked throughout the month
-• Automatic monthly reset (1st of month, 9 AM)
+**📊 %[2]s Process:**
+• Expenses tracked throughout the %[1]s
+• Automatic reset at the end of each %[1]s (next: %[4]s)
 • Data archived to database + CSV export
-• Historical comparison and trend analysis`
+• Historical comparison and trend analysis`,
+		cycle.Noun(), utils.Capitalize(cycle.Adjective()), previous.ID(), h.nextResetText())
 
 	msg := tgbotapi.NewMessage(chatID, helpText)
 	msg.ParseMode = "Markdown"
 	bot.Send(msg)
 }
 
-// SendTransactionHistory sends recent transaction history
-func (h *CommandHandler) SendTransactionHistory(bot *tgbotapi.BotAPI, chatID int64, limit int) {
+// historyHeader starts every /history message; the arguments after it are
+// read back when a page button is tapped
+const historyHeader = "📜 History"
+
+// SendTransactionHistory sends the first page of the transaction history.
+// The arguments after /history narrow it down: "last 20", a period by its
+// ID such as "2025-02" (read from its archive), and any /search filter such
+// as a category or "@user".
+func (h *CommandHandler) SendTransactionHistory(bot *tgbotapi.BotAPI, chatID int64, commandText string) {
+	args := strings.TrimSpace(strings.TrimPrefix(commandText, strings.Fields(commandText)[0]))
+	content, keyboard := h.historyPage(args, 0)
+	msg := tgbotapi.NewMessage(chatID, content)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	bot.Send(msg)
+}
+
+// SendHistoryPage shows another page on a /history message
+func (h *CommandHandler) SendHistoryPage(bot *tgbotapi.BotAPI, chatID int64, messageID int, messageText string, page int) {
+	header, _, _ := strings.Cut(messageText, "\n")
+	if !strings.HasPrefix(header, historyHeader) {
+		return
+	}
+	args := strings.TrimPrefix(strings.TrimPrefix(header, historyHeader), ": ")
+
+	content, keyboard := h.historyPage(args, page)
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, content)
+	editMsg.ReplyMarkup = keyboard
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to update transaction history:", err)
+	}
+}
+
+// historyPage builds one page of the history selected by args
+func (h *CommandHandler) historyPage(args string, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
 	ctx := context.Background()
-	transactions, err := h.db.GetRecentTransactions(ctx, limit)
+	content := historyHeader + "\n"
+	if args != "" {
+		content = historyHeader + ": " + args + "\n"
+	}
+
+	// Pull out the arguments only /history understands; the rest are
+	// search filters
+	limit := 0
+	periodID := ""
+	var filters []string
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		field := strings.ToLower(fields[i])
+		if field == "last" && i+1 < len(fields) {
+			if n, err := strconv.Atoi(fields[i+1]); err == nil && n > 0 {
+				limit = n
+				i++
+				continue
+			}
+		}
+		// A day that doesn't start a period stays a date filter
+		if _, ok := h.config.Cycle.PeriodByID(field); ok {
+			periodID = field
+			continue
+		}
+		filters = append(filters, fields[i])
+	}
+
+	opts := utils.ParseOptions{Categories: h.config.Categories, Aliases: h.config.CategoryAliases, Now: time.Now()}
+	filter, err := utils.ParseSearchQuery(strings.Join(filters, " "), opts)
 	if err != nil {
-		log.Println("Failed to fetch transaction history:", err)
-		msg := tgbotapi.NewMessage(chatID, "Error fetching transaction history.")
+		return fmt.Sprintf("⚠️ Can't show that history: %v", err), nil
+	}
+
+	// The current period is live; earlier ones come from their archive
+	var transactions []models.Transaction
+	if periodID == "" || periodID == h.currentPeriod().ID() {
+		transactions, err = h.db.GetRecentTransactions(ctx, 0)
+		if err != nil {
+			log.Println("Failed to fetch transaction history:", err)
+			return "Error fetching transaction history.", nil
+		}
+	} else {
+		archive, err := h.db.GetMonthlyArchive(ctx, periodID)
+		if err != nil {
+			period, _ := h.config.Cycle.PeriodByID(periodID)
+			return fmt.Sprintf("❌ No archive found for %s", period.Label()), nil
+		}
+		transactions = append(transactions, archive.Transactions...)
+		sort.SliceStable(transactions, func(i, j int) bool {
+			return transactions[i].CreatedAt > transactions[j].CreatedAt
+		})
+	}
+
+	var matches []models.Transaction
+	for _, tx := range transactions {
+		if filter.Matches(&tx) {
+			matches = append(matches, tx)
+		}
+	}
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	if len(matches) == 0 {
+		return content + "\nNo transactions found.", nil
+	}
+
+	list, keyboard := h.transactionPage(matches, page, "history")
+	return content + list, keyboard
+}
+
+// listPageSize is the number of transactions on one page of a list
+const listPageSize = 10
+
+// transactionPage lists one page of transactions, numbered across pages,
+// with the buttons to move between pages (nil when there is one page).
+// Callbacks are prefix followed by the page, e.g. "history_2".
+func (h *CommandHandler) transactionPage(transactions []models.Transaction, page int, prefix string) (string, *tgbotapi.InlineKeyboardMarkup) {
+	pages := (len(transactions) + listPageSize - 1) / listPageSize
+	page = max(0, min(page, pages-1))
+	start := page * listPageSize
+	end := min(start+listPageSize, len(transactions))
+
+	content := fmt.Sprintf("%s, showing %d-%d:\n\n", plural(len(transactions), "transaction"), start+1, end)
+	for i := start; i < end; i++ {
+		content += h.transactionLine(i+1, &transactions[i])
+	}
+
+	if pages == 1 {
+		return content, nil
+	}
+	keyboard := utils.BuildPageKeyboard(prefix, page, pages)
+	return content, &keyboard
+}
+
+// searchHeader starts every /search result message; the query after it is
+// read back when a page button is tapped
+const searchHeader = "🔎 Search: "
+
+// SearchTransactions sends the first page of live and archived
+// transactions matching the filters after /search
+func (h *CommandHandler) SearchTransactions(bot *tgbotapi.BotAPI, chatID int64, commandText string) {
+	query := strings.TrimSpace(strings.TrimPrefix(commandText, strings.Fields(commandText)[0]))
+	if query == "" {
+		msg := tgbotapi.NewMessage(chatID, "Usage: /search <filters>, e.g. /search costco since january, /search 85, /search groceries @alice >50")
 		bot.Send(msg)
 		return
 	}
 
-	if len(transactions) == 0 {
-		msg := tgbotapi.NewMessage(chatID, "No transactions found.")
-		bot.Send(msg)
+	content, keyboard := h.searchPage(query, 0)
+	msg := tgbotapi.NewMessage(chatID, content)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	bot.Send(msg)
+}
+
+// SendSearchPage shows another page of the results on a /search message
+func (h *CommandHandler) SendSearchPage(bot *tgbotapi.BotAPI, chatID int64, messageID int, messageText string, page int) {
+	header, _, _ := strings.Cut(messageText, "\n")
+	if !strings.HasPrefix(header, searchHeader) {
 		return
 	}
 
-	historyText := "**📜 Recent Transactions:**\n"
-	for i, tx := range transactions {
-		timeStr := time.Unix(tx.CreatedAt, 0).Format("Jan 2, 15:04")
-		category := tx.Category
-		if category == "" {
-			category = "Uncategorized"
+	content, keyboard := h.searchPage(strings.TrimPrefix(header, searchHeader), page)
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, content)
+	editMsg.ReplyMarkup = keyboard
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to update search results:", err)
+	}
+}
+
+// searchPage builds one page of search results with totals over all of
+// them, and the buttons to move between pages
+func (h *CommandHandler) searchPage(query string, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	opts := utils.ParseOptions{Categories: h.config.Categories, Aliases: h.config.CategoryAliases, Now: time.Now()}
+	filter, err := utils.ParseSearchQuery(query, opts)
+	if err != nil {
+		return fmt.Sprintf("⚠️ Can't search for that: %v", err), nil
+	}
+
+	matches, err := h.db.SearchTransactions(context.Background(), filter)
+	if err != nil {
+		log.Println("Failed to search transactions:", err)
+		return "Error searching transactions.", nil
+	}
+	content := searchHeader + query + "\n"
+	if len(matches) == 0 {
+		return content + "\nNo transactions found.", nil
+	}
+
+	var spent, refunded, settled models.Money
+	var expenses int
+	for _, tx := range matches {
+		switch {
+		case tx.IsExpense():
+			spent += tx.HomeAmount()
+			expenses++
+		case tx.Kind == models.KindRefund:
+			refunded += tx.HomeAmount()
+		case tx.IsSettlement():
+			settled += tx.HomeAmount()
 		}
-		historyText += fmt.Sprintf("%d. **%.2f$** by %s (%s) - %s\n", 
-			i+1, math.Abs(tx.Amount), tx.Author, category, timeStr)
 	}
 
-	msg := tgbotapi.NewMessage(chatID, historyText)
-	msg.ParseMode = "Markdown"
-	bot.Send(msg)
+	list, keyboard := h.transactionPage(matches, page, "search")
+	content += list
+	content += fmt.Sprintf("\n💵 Spent: %s", h.money(spent-refunded))
+	if expenses > 0 {
+		content += fmt.Sprintf(" (%s, average %s)", plural(expenses, "expense"), h.money(spent.Div(expenses)))
+	}
+	if refunded > 0 {
+		content += fmt.Sprintf("\n↩️ Refunded: %s", h.money(refunded))
+	}
+	if settled > 0 {
+		content += fmt.Sprintf("\n💸 Settled: %s", h.money(settled))
+	}
+	return content, keyboard
+}
+
+// transactionLine formats one numbered line of a transaction list. It is
+// plain text, since list messages are read back when paging.
+func (h *CommandHandler) transactionLine(n int, tx *models.Transaction) string {
+	timeStr := time.Unix(tx.CreatedAt, 0).Format("Jan 2, 15:04")
+	note := ""
+	if tx.Note != "" {
+		note = " 📝 " + tx.Note
+	}
+	switch tx.Kind {
+	case models.KindSettlement:
+		return fmt.Sprintf("%d. 💸 %s %s → %s (Settlement)%s - %s\n",
+			n, utils.FormatTransactionAmount(tx, h.config.HomeCurrency), tx.PaidBy(), tx.To, note, timeStr)
+	case models.KindIncome:
+		return fmt.Sprintf("%d. 💰 %s income received by %s%s%s - %s\n",
+			n, utils.FormatTransactionAmount(tx, h.config.HomeCurrency), tx.PaidBy(), beneficiariesText(tx), note, timeStr)
+	}
+	category := tx.Category
+	if tx.Kind == models.KindRefund {
+		category = "Refund, " + categoryOrDefault(tx.Category)
+	}
+	if category == "" {
+		category = "Uncategorized"
+	}
+	if !tx.Split.IsEqual() {
+		category += ", " + utils.DescribeSplit(tx.Split)
+	}
+	return fmt.Sprintf("%d. %s paid by %s%s (%s)%s - %s\n",
+		n, utils.FormatTransactionAmount(tx, h.config.HomeCurrency), tx.PaidBy(), beneficiariesText(tx), category, note, timeStr)
+}
+
+// beneficiariesText describes who a transaction was for, or nothing when
+// it was for everyone
+func beneficiariesText(tx *models.Transaction) string {
+	if len(tx.Beneficiaries) == 0 {
+		return ""
+	}
+	return " for " + strings.Join(tx.Beneficiaries, ", ")
+}
+
+// money formats an amount in the home currency
+func (h *CommandHandler) money(amount models.Money) string {
+	return utils.FormatHome(amount, h.config.HomeCurrency)
 }
 
-// MonthlyReset performs monthly reset and sends stats
+// noteTotal is what was spent under one note
+type noteTotal struct {
+	Note   string
+	Amount models.Money
+	Count  int
+}
+
+// topNotes totals expenses by note, ignoring case, and returns the n with
+// the most spent
+func topNotes(transactions []models.Transaction, n int) []noteTotal {
+	byNote := make(map[string]*noteTotal)
+	var notes []*noteTotal
+	for _, tx := range transactions {
+		if tx.Note == "" {
+			continue
+		}
+		key := strings.ToLower(tx.Note)
+		total, ok := byNote[key]
+		if !ok {
+			total = &noteTotal{Note: tx.Note}
+			byNote[key] = total
+			notes = append(notes, total)
+		}
+		total.Amount += tx.HomeAmount()
+		total.Count++
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].Amount > notes[j].Amount
+	})
+	var top []noteTotal
+	for _, total := range notes[:min(n, len(notes))] {
+		top = append(top, *total)
+	}
+	return top
+}
+
+// plural counts a noun, e.g. "1 expense" or "3 expenses"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// randomID returns n random bytes in hex, for IDs that must not collide
+func randomID(n int) string {
+	id := make([]byte, n)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// categoryOrDefault returns the category name, or "Uncategorized" if unset
+func categoryOrDefault(category string) string {
+	if category == "" {
+		return "Uncategorized"
+	}
+	return category
+}
+
+// resetLease names the lease held while the periodic reset runs, so two
+// bot processes sharing a database never run it at once
+const resetLease = "reset"
+
+// resetLeaseTTL is how long the reset lease lasts; a process that dies
+// while resetting blocks the next run until then at most
+const resetLeaseTTL = 15 * time.Minute
+
+// takeResetLease takes the reset lease for this run. It reports false if
+// another run holds it, or if the database can't be asked.
+func (h *CommandHandler) takeResetLease() (func(), bool) {
+	ctx := context.Background()
+	owner := randomID(8)
+	ok, err := h.db.AcquireLease(ctx, resetLease, owner, resetLeaseTTL)
+	if err != nil {
+		log.Println("Failed to take the reset lease:", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	return func() {
+		if err := h.db.ReleaseLease(ctx, resetLease, owner); err != nil {
+			log.Println("Failed to release the reset lease:", err)
+		}
+	}, true
+}
+
+// MonthlyReset resets every period of the configured cycle that ended
+// since the last reset. It does nothing when no period has ended, so the
+// job can run daily for cycles cron can't express.
 func (h *CommandHandler) MonthlyReset(bot *tgbotapi.BotAPI) {
+	release, ok := h.takeResetLease()
+	if !ok {
+		log.Println("A reset is already running, skipping")
+		return
+	}
+	defer release()
+
+	periods, err := h.pendingPeriods(context.Background(), time.Now())
+	if err != nil {
+		log.Println("Failed to find the periods to reset:", err)
+		return
+	}
+	if len(periods) == 0 {
+		log.Println("No period ended since the last reset")
+		return
+	}
+	for _, period := range periods {
+		// Later periods wait for a failed one, so the recorded progress
+		// never skips it
+		if _, ok := h.resetPeriod(bot, period, false); !ok {
+			break
+		}
+	}
+}
+
+// CatchUpResets runs the resets missed while the bot was down, then tells
+// the chat what it caught up on. Periods without transactions are
+// recorded without a report.
+func (h *CommandHandler) CatchUpResets(bot *tgbotapi.BotAPI) {
+	release, ok := h.takeResetLease()
+	if !ok {
+		log.Println("A reset is already running, skipping catch-up")
+		return
+	}
+	defer release()
+
+	now := time.Now()
+	periods, err := h.pendingPeriods(context.Background(), now)
+	if err != nil {
+		log.Println("Failed to find missed resets:", err)
+		return
+	}
+
+	var lines []string
+	for _, period := range periods {
+		if !h.resetMissed(period, now) {
+			// Not due yet; the scheduled job will run it
+			break
+		}
+		count, archived := h.resetPeriod(bot, period, true)
+		if !archived {
+			lines = append(lines, fmt.Sprintf("• %s: ⚠️ archive failed, retrying on the next run", period.Label()))
+			break
+		}
+		if count > 0 {
+			lines = append(lines, fmt.Sprintf("• %s: %s archived", period.Label(), plural(count, "transaction")))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	content := fmt.Sprintf("⏰ The bot was offline when a reset was due. Caught up on:\n%s", strings.Join(lines, "\n"))
+	msg := tgbotapi.NewMessage(h.config.ChatID, content)
+	bot.Send(msg)
+}
+
+// resetMissed checks if the scheduled reset of a period should have run
+// by now
+func (h *CommandHandler) resetMissed(period models.Period, now time.Time) bool {
+	due, err := h.resetDue(period)
+	if err != nil {
+		return true
+	}
+	return !due.After(now)
+}
+
+// pendingPeriods returns the periods that ended since the last completed
+// reset, oldest first. Before the first reset only the period that just
+// ended is due.
+func (h *CommandHandler) pendingPeriods(ctx context.Context, now time.Time) ([]models.Period, error) {
+	cycle := h.config.Cycle
+	current := cycle.PeriodAt(now.In(h.location()))
+
+	state, err := h.db.GetResetState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return []models.Period{cycle.Previous(current)}, nil
+	}
+
+	var periods []models.Period
+	for period := cycle.Previous(current); period.End.Unix() > state.PeriodEnd; period = cycle.Previous(period) {
+		periods = append([]models.Period{period}, periods...)
+	}
+	return periods, nil
+}
+
+// resetPeriod archives a period that ended, which clears its transactions,
+// and sends its stats. Transactions dated after the period carry over. A
+// quiet reset doesn't report a period without transactions. It returns the
+// number of transactions archived, and whether archiving succeeded.
+func (h *CommandHandler) resetPeriod(bot *tgbotapi.BotAPI, period models.Period, quiet bool) (int, bool) {
 	ctx := context.Background()
 	chatID := h.config.ChatID
+	noun := h.config.Cycle.Noun()
+
+	// A period archived before, whose reset wasn't recorded, is done. Only
+	// an archive of exactly this period counts; one with the same ID that
+	// covers other dates is moved out of the way, so two periods never
+	// share an archive.
+	if existing, err := h.db.GetMonthlyArchive(ctx, period.ID()); err == nil && existing != nil {
+		if existing.PeriodStart == period.Start.Unix() && existing.PeriodEnd == period.End.Unix() {
+			log.Printf("%s is already archived, skipping", period.Label())
+			h.saveResetState(ctx, period)
+			return 0, true
+		}
+		if err := h.moveArchive(ctx, existing); err != nil {
+			log.Printf("Moving archive %s failed, nothing was cleared: %v", existing.ID, err)
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Archiving %s failed, so its transactions were kept. The reset will be retried on the next run.", period.Label()))
+			bot.Send(msg)
+			return 0, false
+		}
+	}
 
-	// Archive current month's data (with fallback)
+	// Archive the period and clear exactly its transactions in one step.
+	// Nothing is cleared when that fails, and the next run retries.
 	var archive *models.MonthlyArchive
-	archiveErr := h.safeArchiveData(ctx, &archive)
-	if archiveErr != nil {
-		log.Printf("Archive failed but continuing with reset: %v", archiveErr)
+	var archived int
+	archiveErr := h.safeArchiveData(ctx, period, &archive, &archived)
+	empty := errors.Is(archiveErr, database.ErrNothingToArchive)
+	if archiveErr != nil && !empty {
+		log.Printf("Archive of %s failed, nothing was cleared: %v", period.Label(), archiveErr)
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Archiving %s failed, so its transactions were kept. The reset will be retried on the next run.", period.Label()))
+		bot.Send(msg)
+		return 0, false
+	}
+	if empty && quiet {
+		h.saveResetState(ctx, period)
+		return 0, true
 	}
 
-	// Get current data for the report (fallback to recalculation if archive failed)
-	var balance, totalSpent float64
-	var categoryTotals, userTotals map[string]float64
+	// Get the archived data for the report; an empty period has none
+	var totalSpent, refunded, income models.Money
+	var categoryTotals map[string]models.Money
+	var balances []models.MemberBalance
 	var transactions []models.Transaction
-	var totalTransactions int
+	var expenses int
 
 	if archive != nil {
-		// Use archived data
-		balance = archive.Balance
 		totalSpent = archive.TotalSpent
+		refunded = archive.TotalRefunded
+		income = archive.TotalIncome
 		categoryTotals = archive.CategoryTotals
-		userTotals = archive.UserTotals
-		transactions = archive.Transactions
-		totalTransactions = archive.TotalTransactions
-	} else {
-		// Fallback: calculate fresh data
-		var err error
-		balance, categoryTotals, userTotals, err = h.db.CalculateTotals(ctx)
-		if err != nil {
-			log.Println("Failed to calculate totals for monthly reset:", err)
-			return
-		}
-		transactions, _ = h.db.GetAllTransactions(ctx)
-		totalTransactions = len(transactions)
-		for _, amt := range categoryTotals {
-			totalSpent += amt
-		}
+		balances = archive.Balances
+		transactions = models.Expenses(archive.Transactions)
+		expenses = archive.TotalTransactions
 	}
-	
+
 	var monthlyText string
-	monthlyText += "📅 **MONTHLY EXPENSE REPORT**\n"
+	monthlyText += fmt.Sprintf("📅 **%s EXPENSE REPORT**\n", strings.ToUpper(period.Label()))
 	monthlyText += "════════════\n\n"
 
-	if totalTransactions == 0 {
-		monthlyText += "❌ No transactions this month\n"
+	if archived == 0 {
+		monthlyText += fmt.Sprintf("❌ No transactions this %s\n", noun)
+	} else if expenses == 0 {
+		monthlyText += fmt.Sprintf("❌ No expenses this %s, %s archived\n", noun, plural(archived, "other transaction"))
 	} else {
-		monthlyText += fmt.Sprintf("📊 **Month Summary:**\n")
-		monthlyText += fmt.Sprintf("   • Total transactions: %d\n", totalTransactions)
-		monthlyText += fmt.Sprintf("   • Total spent: **%.2f$**\n", totalSpent)
-		if totalTransactions > 0 {
-			monthlyText += fmt.Sprintf("   • Average per transaction: %.2f$\n", totalSpent/float64(totalTransactions))
+		monthlyText += fmt.Sprintf("📊 **%s Summary:**\n", utils.Capitalize(noun))
+		monthlyText += fmt.Sprintf("   • Expenses: %d\n", expenses)
+		monthlyText += fmt.Sprintf("   • Total spent: **%s**\n", h.money(totalSpent))
+		if refunded > 0 {
+			monthlyText += fmt.Sprintf("   • Refunds: %s (already deducted)\n", h.money(refunded))
 		}
-		monthlyText += fmt.Sprintf("   • Average per day: %.2f$\n\n", totalSpent/30.0) // Approximate
-
-		// Add archive status
-		if archive != nil {
-			monthlyText += "💾 **Archive Status:** ✅ Data archived successfully\n\n"
-		} else {
-			monthlyText += "💾 **Archive Status:** ⚠️ Archive failed (data in report only)\n\n"
+		if income > 0 {
+			monthlyText += fmt.Sprintf("   • Shared income: %s\n", h.money(income))
 		}
+		monthlyText += fmt.Sprintf("   • Average per expense: %s\n", h.money(totalSpent.Div(expenses)))
+		monthlyText += fmt.Sprintf("   • Average per day: %s\n\n", h.money(totalSpent.Div(period.Days())))
 
-		// Final balance
-		var users []string
-		for user := range userTotals {
-			users = append(users, user)
-		}
+		monthlyText += fmt.Sprintf("💾 **Archive Status:** ✅ %s archived\n\n", plural(archived, "transaction"))
 
-		if len(users) >= 2 {
+		// Final balance
+		if len(balances) > 0 {
 			monthlyText += "💰 **Final Balance:**\n"
-			if balance > 0 {
-				monthlyText += fmt.Sprintf("   %s owes **%.2f$** to %s\n\n", users[1], math.Abs(balance), users[0])
-			} else if balance < 0 {
-				monthlyText += fmt.Sprintf("   %s owes **%.2f$** to %s\n\n", users[0], math.Abs(balance), users[1])
-			} else {
-				monthlyText += "   ✅ Perfect balance! (0$)\n\n"
-			}
+			monthlyText += h.formatBalances(balances, fmt.Sprintf("   ✅ Perfect balance! (%s)\n", h.money(0)))
+			monthlyText += "\n"
 
 			// User spending breakdown
 			monthlyText += "👥 **User Spending:**\n"
-			for user, amount := range userTotals {
-				percentage := (amount / totalSpent) * 100
-				monthlyText += fmt.Sprintf("   %s: %.2f$ (%.1f%%)\n", user, amount, percentage)
+			for _, b := range balances {
+				percentage := (b.Paid.Float() / totalSpent.Float()) * 100
+				monthlyText += fmt.Sprintf("   %s: %s (%.1f%%)\n", b.User, h.money(b.Paid), percentage)
 			}
 			monthlyText += "\n"
 		}
@@ -352,13 +878,13 @@ func (h *CommandHandler) MonthlyReset(bot *tgbotapi.BotAPI) {
 			// Sort categories by amount
 			type CategoryData struct {
 				Name   string
-				Amount float64
+				Amount models.Money
 				Percent float64
 			}
 			
 			var categories []CategoryData
 			for cat, amt := range categoryTotals {
-				percent := (amt / totalSpent) * 100
+				percent := (amt.Float() / totalSpent.Float()) * 100
 				categories = append(categories, CategoryData{cat, amt, percent})
 			}
 			
@@ -385,19 +911,19 @@ func (h *CommandHandler) MonthlyReset(bot *tgbotapi.BotAPI) {
 				} else if i == 2 {
 					medal = "🥉"
 				}
-				monthlyText += fmt.Sprintf("   %s %s: %.2f$ (%.1f%%)\n", medal, cat.Name, cat.Amount, cat.Percent)
+				monthlyText += fmt.Sprintf("   %s %s: %s (%.1f%%)\n", medal, cat.Name, h.money(cat.Amount), cat.Percent)
 			}
 			monthlyText += "\n"
 		}
 
 		// Fun insights
-		monthlyText += "🎯 **Month Insights:**\n"
+		monthlyText += fmt.Sprintf("🎯 **%s Insights:**\n", utils.Capitalize(noun))
 		if len(transactions) > 0 {
 			// Find highest and lowest transaction
-			highestAmount := 0.0
-			lowestAmount := math.MaxFloat64
+			var highestAmount models.Money
+			lowestAmount := models.Money(math.MaxInt64)
 			for _, tx := range transactions {
-				amt := math.Abs(tx.Amount)
+				amt := tx.HomeAmount()
 				if amt > highestAmount {
 					highestAmount = amt
 				}
@@ -406,8 +932,15 @@ func (h *CommandHandler) MonthlyReset(bot *tgbotapi.BotAPI) {
 				}
 			}
 			
-			monthlyText += fmt.Sprintf("   • Biggest splurge: %.2f$\n", highestAmount)
-			monthlyText += fmt.Sprintf("   • Smallest expense: %.2f$\n", lowestAmount)
+			biggest := h.money(highestAmount)
+			for _, tx := range transactions {
+				if tx.HomeAmount() == highestAmount && tx.Note != "" {
+					biggest += fmt.Sprintf(" (%s)", tx.Note)
+					break
+				}
+			}
+			monthlyText += fmt.Sprintf("   • Biggest splurge: %s\n", biggest)
+			monthlyText += fmt.Sprintf("   • Smallest expense: %s\n", h.money(lowestAmount))
 			
 			// Calculate days with spending
 			uniqueDays := make(map[string]bool)
@@ -416,15 +949,24 @@ func (h *CommandHandler) MonthlyReset(bot *tgbotapi.BotAPI) {
 				uniqueDays[day] = true
 			}
 			monthlyText += fmt.Sprintf("   • Days with spending: %d\n", len(uniqueDays))
+
+			// Where the money went, by note
+			if top := topNotes(transactions, 3); len(top) > 0 {
+				monthlyText += "\n🏪 **Top Merchants:**\n"
+				for _, note := range top {
+					monthlyText += fmt.Sprintf("   %s: %s (%d×)\n", note.Note, h.money(note.Amount), note.Count)
+				}
+			}
 		}
 	}
 
-	monthlyText += "\n🔄 **Starting fresh for next month!**\n"
+	monthlyText += fmt.Sprintf("\n🔄 **Starting fresh for the next %s!**\n", noun)
 	if archive != nil {
-		monthlyText += "All transactions have been archived.\n"
+		monthlyText += fmt.Sprintf("All transactions from %s have been archived.\n", period.Label())
 		monthlyText += "📊 CSV export will be sent shortly..."
-	} else {
-		monthlyText += "Transactions cleared (archive failed)."
+	}
+	if carried := h.countAfter(ctx, period); carried > 0 {
+		monthlyText += fmt.Sprintf("\n➡️ Carried over %s dated after %s.", plural(carried, "transaction"), period.Label())
 	}
 
 	// Send the text report first
@@ -437,32 +979,117 @@ func (h *CommandHandler) MonthlyReset(bot *tgbotapi.BotAPI) {
 		h.safeExportCSV(bot, chatID, archive)
 	}
 
-	// Clear DB (with error handling)
-	err := h.db.DeleteAllTransactions(ctx)
+	h.saveResetState(ctx, period)
+	log.Println("Periodic reset complete.")
+	return archived, true
+}
+
+// saveResetState records period as the last one the periodic reset
+// completed
+func (h *CommandHandler) saveResetState(ctx context.Context, period models.Period) {
+	state := models.NewResetState(period, time.Now().Unix())
+	if err := h.db.SaveResetState(ctx, state); err != nil {
+		log.Println("Failed to record the completed reset:", err)
+	}
+}
+
+// location returns the timezone periods are cut in
+func (h *CommandHandler) location() *time.Location {
+	if h.config.Location == nil {
+		return time.Local
+	}
+	return h.config.Location
+}
+
+// currentPeriod returns the period of the configured cycle that is running
+func (h *CommandHandler) currentPeriod() models.Period {
+	return h.config.Cycle.PeriodAt(time.Now().In(h.location()))
+}
+
+// resetDue returns when the scheduled job resets a period: its first run
+// once the period has ended
+func (h *CommandHandler) resetDue(period models.Period) (time.Time, error) {
+	schedule, err := cron.ParseStandard(h.config.ResetSchedule)
 	if err != nil {
-		log.Println("Failed to delete monthly data:", err)
-		// Send error message to user
-		errorMsg := tgbotapi.NewMessage(chatID, "⚠️ Warning: Failed to clear transactions. Manual cleanup may be needed.")
-		bot.Send(errorMsg)
-	} else {
-		log.Println("Monthly reset complete.")
+		return time.Time{}, err
+	}
+	return schedule.Next(period.End.In(h.location()).Add(-time.Second)), nil
+}
+
+// nextResetText tells when the running period will be reset, for /help
+func (h *CommandHandler) nextResetText() string {
+	due, err := h.resetDue(h.currentPeriod())
+	if err != nil {
+		return "schedule " + h.config.ResetSchedule
+	}
+	return due.Format("Mon Jan 2, 15:04")
+}
+
+// countAfter counts the transactions dated after period, which carry over
+// to the next one
+func (h *CommandHandler) countAfter(ctx context.Context, period models.Period) int {
+	transactions, err := h.db.GetAllTransactions(ctx)
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, tx := range transactions {
+		if tx.CreatedAt >= period.End.Unix() {
+			count++
+		}
+	}
+	return count
+}
+
+// moveArchive moves an archive out of the way of a period with the same
+// ID. One from before periods were recorded is keyed by the month its
+// reset ran in but holds the month before, so it gets that month's ID and
+// dates. When that ID is taken a numbered one is used.
+func (h *CommandHandler) moveArchive(ctx context.Context, archive *models.MonthlyArchive) error {
+	moved := *archive
+	base := archive.ID
+	if archive.PeriodStart == 0 {
+		ranIn := time.Date(archive.Year, time.Month(archive.Month), 1, 0, 0, 0, 0, h.location())
+		period := models.MonthPeriod(ranIn.AddDate(0, -1, 0))
+		moved.Year = period.Start.Year()
+		moved.Month = int(period.Start.Month())
+		moved.MonthName = period.Start.Format("January")
+		moved.Label = period.Label()
+		moved.PeriodStart = period.Start.Unix()
+		moved.PeriodEnd = period.End.Unix()
+		base = period.ID()
+	}
+
+	moved.ID = base
+	for n := 2; moved.ID == archive.ID || h.archiveExists(ctx, moved.ID); n++ {
+		moved.ID = fmt.Sprintf("%s-%d", base, n)
 	}
+	log.Printf("Moving archive %s (%s) to %s", archive.ID, moved.PeriodLabel(), moved.ID)
+	return h.db.MoveArchive(ctx, archive.ID, &moved)
 }
 
-// safeArchiveData safely archives monthly data with error handling
-func (h *CommandHandler) safeArchiveData(ctx context.Context, archive **models.MonthlyArchive) error {
+// archiveExists checks if an archive with the ID is stored
+func (h *CommandHandler) archiveExists(ctx context.Context, id string) bool {
+	archive, err := h.db.GetMonthlyArchive(ctx, id)
+	return err == nil && archive != nil
+}
+
+// safeArchiveData safely archives a period's data with error handling
+func (h *CommandHandler) safeArchiveData(ctx context.Context, period models.Period, archive **models.MonthlyArchive, archived *int) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("Archive panic recovered: %v", r)
+			err = fmt.Errorf("archive panicked: %v", r)
 		}
 	}()
 
-	archiveData, err := h.db.ArchiveMonthlyData(ctx)
+	archiveData, count, err := h.db.ArchivePeriod(ctx, period)
 	if err != nil {
 		return fmt.Errorf("failed to archive: %w", err)
 	}
 
 	*archive = archiveData
+	*archived = count
 	return nil
 }
 
@@ -479,7 +1106,7 @@ func (h *CommandHandler) safeExportCSV(bot *tgbotapi.BotAPI, chatID int64, archi
 
 	// Generate CSV
 	var buffer bytes.Buffer
-	err := utils.GenerateMonthlyCSV(archive, &buffer)
+	err := utils.GeneratePeriodCSV(archive, h.config.Cycle, &buffer)
 	if err != nil {
 		log.Printf("Failed to generate CSV: %v", err)
 		msg := tgbotapi.NewMessage(chatID, "⚠️ CSV generation failed. Data is still archived in database.")
@@ -489,6 +1116,10 @@ func (h *CommandHandler) safeExportCSV(bot *tgbotapi.BotAPI, chatID int64, archi
 
 	// Create filename
 	filename := fmt.Sprintf("expenses_%s_%d.csv", archive.MonthName, archive.Year)
+	if strings.Count(archive.ID, "-") == 2 {
+		// Periods other than calendar months are named by their first day
+		filename = fmt.Sprintf("expenses_%s.csv", archive.ID)
+	}
 	
 	// Send CSV file
 	document := tgbotapi.FileBytes{
@@ -497,8 +1128,8 @@ func (h *CommandHandler) safeExportCSV(bot *tgbotapi.BotAPI, chatID int64, archi
 	}
 
 	documentMsg := tgbotapi.NewDocument(chatID, document)
-	documentMsg.Caption = fmt.Sprintf("📊 Monthly expense data for %s %d\n💾 %d transactions, %.2f$ total", 
-		archive.MonthName, archive.Year, archive.TotalTransactions, archive.TotalSpent)
+	documentMsg.Caption = fmt.Sprintf("📊 Expense data for %s\n💾 %s, %s spent",
+		archive.PeriodLabel(), plural(len(archive.Transactions), "transaction"), h.money(archive.TotalSpent))
 
 	_, err = bot.Send(documentMsg)
 	if err != nil {
@@ -512,22 +1143,24 @@ func (h *CommandHandler) safeExportCSV(bot *tgbotapi.BotAPI, chatID int64, archi
 func (h *CommandHandler) SendMonthlyComparison(bot *tgbotapi.BotAPI, chatID int64) {
 	ctx := context.Background()
 	
+	noun := h.config.Cycle.Noun()
+	
 	archives, err := h.db.GetRecentArchives(ctx, 3)
 	if err != nil || len(archives) == 0 {
-		msg := tgbotapi.NewMessage(chatID, "❌ No archived months found for comparison.\nUse the bot for a month and wait for monthly reset to generate archives.")
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ No archived %ss found for comparison.\nUse the bot for a %s and wait for the reset to generate archives.", noun, noun))
 		bot.Send(msg)
 		return
 	}
 
 	if len(archives) == 1 {
-		msg := tgbotapi.NewMessage(chatID, "📊 Only one month archived. Need at least 2 months for comparison.\nCheck back after next monthly reset!")
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("📊 Only one %s archived. Need at least 2 %ss for comparison.\nCheck back after the next reset!", noun, noun))
 		bot.Send(msg)
 		return
 	}
 
 	// Generate comparison text
 	var comparisonText string
-	comparisonText += "📊 **MONTHLY COMPARISON**\n"
+	comparisonText += fmt.Sprintf("📊 **%s COMPARISON**\n", strings.ToUpper(h.config.Cycle.Adjective()))
 	comparisonText += "═══════════════════════\n\n"
 
 	// Summary table
@@ -537,34 +1170,34 @@ func (h *CommandHandler) SendMonthlyComparison(bot *tgbotapi.BotAPI, chatID int6
 		if i == 0 {
 			emoji = "🆕" // Most recent
 		}
-		comparisonText += fmt.Sprintf("%s %s %d: **%.2f$** (%d transactions)\n", 
-			emoji, archive.MonthName, archive.Year, archive.TotalSpent, archive.TotalTransactions)
+		comparisonText += fmt.Sprintf("%s %s: **%s** (%s)\n",
+			emoji, archive.PeriodLabel(), h.money(archive.TotalSpent), plural(archive.TotalTransactions, "expense"))
 	}
 	comparisonText += "\n"
 
-	// Month-over-month changes
+	// Period-over-period changes
 	if len(archives) >= 2 {
 		current := archives[0]
 		previous := archives[1]
 		
 		spendingChange := current.TotalSpent - previous.TotalSpent
-		spendingPercent := (spendingChange / previous.TotalSpent) * 100
+		spendingPercent := (spendingChange.Float() / previous.TotalSpent.Float()) * 100
 		
 		transactionChange := current.TotalTransactions - previous.TotalTransactions
 		
-		comparisonText += "📈 **Month-over-Month:**\n"
+		comparisonText += fmt.Sprintf("📈 **%s-over-%s:**\n", utils.Capitalize(noun), utils.Capitalize(noun))
 		
 		spendingEmoji := "📈"
 		if spendingChange < 0 {
 			spendingEmoji = "📉"
 		}
-		comparisonText += fmt.Sprintf("%s Spending: %.2f$ (%+.1f%%)\n", spendingEmoji, spendingChange, spendingPercent)
+		comparisonText += fmt.Sprintf("%s Spending: %s (%+.1f%%)\n", spendingEmoji, h.money(spendingChange), spendingPercent)
 		
 		transactionEmoji := "📈"
 		if transactionChange < 0 {
 			transactionEmoji = "📉"
 		}
-		comparisonText += fmt.Sprintf("%s Transactions: %+d\n\n", transactionEmoji, transactionChange)
+		comparisonText += fmt.Sprintf("%s Expenses: %+d\n\n", transactionEmoji, transactionChange)
 	}
 
 	// Category comparison (top categories)
@@ -573,10 +1206,10 @@ func (h *CommandHandler) SendMonthlyComparison(bot *tgbotapi.BotAPI, chatID int6
 		current := archives[0]
 		previous := archives[1]
 		
-		// Get top 3 categories from current month
+		// Get top 3 categories from the latest period
 		type CategoryData struct {
 			Name   string
-			Amount float64
+			Amount models.Money
 		}
 		
 		var currentCategories []CategoryData
@@ -606,7 +1239,7 @@ func (h *CommandHandler) SendMonthlyComparison(bot *tgbotapi.BotAPI, chatID int6
 			var changeText string
 			if previousAmount > 0 {
 				change := currentAmount - previousAmount
-				changePercent := (change / previousAmount) * 100
+				changePercent := (change.Float() / previousAmount.Float()) * 100
 				if change > 0 {
 					changeText = fmt.Sprintf(" (+%.1f%%)", changePercent)
 				} else if change < 0 {
@@ -618,7 +1251,7 @@ func (h *CommandHandler) SendMonthlyComparison(bot *tgbotapi.BotAPI, chatID int6
 				changeText = " (new category)"
 			}
 			
-			comparisonText += fmt.Sprintf("   %s: %.2f$%s\n", cat.Name, currentAmount, changeText)
+			comparisonText += fmt.Sprintf("   %s: %s%s\n", cat.Name, h.money(currentAmount), changeText)
 		}
 		comparisonText += "\n"
 	}
@@ -656,7 +1289,9 @@ func (h *CommandHandler) SendMonthlyComparison(bot *tgbotapi.BotAPI, chatID int6
 func (h *CommandHandler) SendSpendingTrends(bot *tgbotapi.BotAPI, chatID int64) {
 	ctx := context.Background()
 	
-	archives, err := h.db.GetRecentArchives(ctx, 6) // Last 6 months
+	noun := h.config.Cycle.Noun()
+	
+	archives, err := h.db.GetRecentArchives(ctx, 6) // Last 6 periods
 	if err != nil || len(archives) == 0 {
 		msg := tgbotapi.NewMessage(chatID, "❌ No archived data found for trend analysis.")
 		bot.Send(msg)
@@ -668,8 +1303,8 @@ func (h *CommandHandler) SendSpendingTrends(bot *tgbotapi.BotAPI, chatID int64)
 	trendsText += "═══════════════════════════════\n\n"
 
 	// Spending trend over time
-	trendsText += "💰 **Monthly Spending Trend:**\n"
-	totalSpent := 0.0
+	trendsText += fmt.Sprintf("💰 **%s Spending Trend:**\n", utils.Capitalize(h.config.Cycle.Adjective()))
+	var totalSpent models.Money
 	for i := len(archives) - 1; i >= 0; i-- { // Show chronologically
 		archive := archives[i]
 		trendEmoji := "📊"
@@ -679,36 +1314,36 @@ func (h *CommandHandler) SendSpendingTrends(bot *tgbotapi.BotAPI, chatID int64)
 			trendEmoji = "📉"
 		}
 		
-		trendsText += fmt.Sprintf("%s %s %d: %.2f$\n", trendEmoji, archive.MonthName, archive.Year, archive.TotalSpent)
+		trendsText += fmt.Sprintf("%s %s: %s\n", trendEmoji, archive.PeriodLabel(), h.money(archive.TotalSpent))
 		totalSpent += archive.TotalSpent
 	}
 	
-	avgMonthlySpending := totalSpent / float64(len(archives))
-	trendsText += fmt.Sprintf("\n📊 **Average Monthly Spending:** %.2f$\n\n", avgMonthlySpending)
+	avgSpending := totalSpent.Div(len(archives))
+	trendsText += fmt.Sprintf("\n📊 **Average %s Spending:** %s\n\n", utils.Capitalize(h.config.Cycle.Adjective()), h.money(avgSpending))
 
 	// Category trends
-	categoryTotals := make(map[string]float64)
-	categoryMonths := make(map[string]int)
+	categoryTotals := make(map[string]models.Money)
+	categoryPeriods := make(map[string]int)
 	
 	for _, archive := range archives {
 		for cat, amount := range archive.CategoryTotals {
 			categoryTotals[cat] += amount
-			categoryMonths[cat]++
+			categoryPeriods[cat]++
 		}
 	}
 	
 	if len(categoryTotals) > 0 {
-		trendsText += "🏷️ **Category Trends (Avg/Month):**\n"
+		trendsText += fmt.Sprintf("🏷️ **Category Trends (Avg/%s):**\n", utils.Capitalize(noun))
 		
 		// Sort categories by total spending
 		type CategoryAvg struct {
 			Name string
-			Avg  float64
+			Avg  models.Money
 		}
 		
 		var categoryAvgs []CategoryAvg
 		for cat, total := range categoryTotals {
-			avg := total / float64(categoryMonths[cat])
+			avg := total.Div(categoryPeriods[cat])
 			categoryAvgs = append(categoryAvgs, CategoryAvg{cat, avg})
 		}
 		
@@ -722,29 +1357,29 @@ func (h *CommandHandler) SendSpendingTrends(bot *tgbotapi.BotAPI, chatID int64)
 		}
 		
 		for _, catAvg := range categoryAvgs {
-			percentage := (catAvg.Avg / avgMonthlySpending) * 100
-			trendsText += fmt.Sprintf("   %s: %.2f$/month (%.1f%%)\n", catAvg.Name, catAvg.Avg, percentage)
+			percentage := (catAvg.Avg.Float() / avgSpending.Float()) * 100
+			trendsText += fmt.Sprintf("   %s: %s/%s (%.1f%%)\n", catAvg.Name, h.money(catAvg.Avg), noun, percentage)
 		}
 		trendsText += "\n"
 	}
 
-	// Transaction patterns
-	totalTransactions := 0
+	// Transaction patterns, counting expenses like the archives do
+	totalExpenses := 0
 	for _, archive := range archives {
-		totalTransactions += archive.TotalTransactions
+		totalExpenses += archive.TotalTransactions
 	}
-	avgTransactionsPerMonth := float64(totalTransactions) / float64(len(archives))
+	avgExpensesPerPeriod := float64(totalExpenses) / float64(len(archives))
 	
 	trendsText += "📱 **Transaction Patterns:**\n"
-	trendsText += fmt.Sprintf("   • Avg transactions/month: %.1f\n", avgTransactionsPerMonth)
-	trendsText += fmt.Sprintf("   • Total months analyzed: %d\n", len(archives))
-	trendsText += fmt.Sprintf("   • Total transactions: %d\n\n", totalTransactions)
+	trendsText += fmt.Sprintf("   • Avg expenses/%s: %.1f\n", noun, avgExpensesPerPeriod)
+	trendsText += fmt.Sprintf("   • Total %ss analyzed: %d\n", noun, len(archives))
+	trendsText += fmt.Sprintf("   • Total expenses: %d\n\n", totalExpenses)
 
 	// Seasonal insights (if we have enough data)
 	if len(archives) >= 3 {
 		trendsText += "🔍 **Insights:**\n"
 		
-		// Find highest and lowest spending months
+		// Find highest and lowest spending periods
 		highest := archives[0]
 		lowest := archives[0]
 		
@@ -757,17 +1392,17 @@ func (h *CommandHandler) SendSpendingTrends(bot *tgbotapi.BotAPI, chatID int64)
 			}
 		}
 		
-		trendsText += fmt.Sprintf("   • Highest spending: %s %d (%.2f$)\n", highest.MonthName, highest.Year, highest.TotalSpent)
-		trendsText += fmt.Sprintf("   • Lowest spending: %s %d (%.2f$)\n", lowest.MonthName, lowest.Year, lowest.TotalSpent)
+		trendsText += fmt.Sprintf("   • Highest spending: %s (%s)\n", highest.PeriodLabel(), h.money(highest.TotalSpent))
+		trendsText += fmt.Sprintf("   • Lowest spending: %s (%s)\n", lowest.PeriodLabel(), h.money(lowest.TotalSpent))
 		
 		// Volatility
 		variance := 0.0
 		for _, archive := range archives {
-			diff := archive.TotalSpent - avgMonthlySpending
+			diff := (archive.TotalSpent - avgSpending).Float()
 			variance += diff * diff
 		}
 		stdDev := math.Sqrt(variance / float64(len(archives)))
-		volatility := (stdDev / avgMonthlySpending) * 100
+		volatility := (stdDev / avgSpending.Float()) * 100
 		
 		trendsText += fmt.Sprintf("   • Spending volatility: %.1f%%\n", volatility)
 		
@@ -785,18 +1420,20 @@ func (h *CommandHandler) SendSpendingTrends(bot *tgbotapi.BotAPI, chatID int64)
 	bot.Send(msg)
 }
 
-// ExportMonthlyData exports specific month data or comparison
+// ExportMonthlyData exports the archive of a period or the comparison
 func (h *CommandHandler) ExportMonthlyData(bot *tgbotapi.BotAPI, chatID int64, commandText string) {
 	ctx := context.Background()
+	cycle := h.config.Cycle
+	example := cycle.Previous(h.currentPeriod()).ID()
 	
 	// Parse command arguments
 	args := strings.Fields(commandText)
 	
 	if len(args) == 1 {
-		// No arguments - export most recent month
+		// No arguments - export most recent period
 		archives, err := h.db.GetRecentArchives(ctx, 1)
 		if err != nil || len(archives) == 0 {
-			msg := tgbotapi.NewMessage(chatID, "❌ No archived data found.\nUsage: /export [month-year] or /export compare")
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ No archived data found.\nUsage: /export [%s] or /export compare", example))
 			bot.Send(msg)
 			return
 		}
@@ -809,14 +1446,14 @@ func (h *CommandHandler) ExportMonthlyData(bot *tgbotapi.BotAPI, chatID int64, c
 		// Export comparison CSV
 		archives, err := h.db.GetRecentArchives(ctx, 6)
 		if err != nil || len(archives) < 2 {
-			msg := tgbotapi.NewMessage(chatID, "❌ Need at least 2 archived months for comparison.")
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Need at least 2 archived %ss for comparison.", cycle.Noun()))
 			bot.Send(msg)
 			return
 		}
 		
 		// Generate comparison CSV
 		var buffer bytes.Buffer
-		err = utils.GenerateComparisonCSV(archives, &buffer)
+		err = utils.GenerateComparisonCSV(archives, h.config.Cycle, &buffer)
 		if err != nil {
 			log.Printf("Failed to generate comparison CSV: %v", err)
 			msg := tgbotapi.NewMessage(chatID, "⚠️ Failed to generate comparison CSV.")
@@ -825,39 +1462,32 @@ func (h *CommandHandler) ExportMonthlyData(bot *tgbotapi.BotAPI, chatID int64, c
 		}
 		
 		// Send file
-		filename := fmt.Sprintf("comparison_%s.csv", time.Now().Format("2006-01"))
+		filename := fmt.Sprintf("comparison_%s.csv", h.currentPeriod().ID())
 		document := tgbotapi.FileBytes{
 			Name:  filename,
 			Bytes: buffer.Bytes(),
 		}
 		
 		documentMsg := tgbotapi.NewDocument(chatID, document)
-		documentMsg.Caption = fmt.Sprintf("📊 Monthly comparison report\n📈 %d months analyzed", len(archives))
+		documentMsg.Caption = fmt.Sprintf("📊 %s comparison report\n📈 %s analyzed", utils.Capitalize(cycle.Adjective()), plural(len(archives), cycle.Noun()))
 		
 		bot.Send(documentMsg)
 		return
 	}
 	
-	// Try to parse specific month (format: YYYY-MM or Month-YYYY)
-	monthID := ""
-	if len(args) >= 2 {
-		// Try different formats
-		arg := args[1]
-		if len(arg) == 7 && arg[4] == '-' {
-			// Format: 2025-01
-			monthID = arg
-		} else {
-			// Try to parse other formats (Month-Year, etc.)
-			msg := tgbotapi.NewMessage(chatID, "❌ Invalid format. Use: /export 2025-01 or /export compare")
-			bot.Send(msg)
-			return
-		}
+	// Export a specific period by its ID, e.g. 2025-01 or 2025-03-15
+	periodID := args[1]
+	period, ok := cycle.PeriodByID(periodID)
+	if !ok {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Invalid format. Use: /export %s or /export compare", example))
+		bot.Send(msg)
+		return
 	}
 	
-	// Get specific month archive
-	archive, err := h.db.GetMonthlyArchive(ctx, monthID)
+	// Get the period's archive
+	archive, err := h.db.GetMonthlyArchive(ctx, periodID)
 	if err != nil {
-		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ No archive found for %s", monthID))
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ No archive found for %s", period.Label()))
 		bot.Send(msg)
 		return
 	}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+	"telegram-expense-bot/internal/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// reconcileWindow is how far back the reconciler looks for transactions
+// whose message was deleted
+const reconcileWindow = 48 * time.Hour
+
+// reconcileBatch is the most messages one reconciler run checks, and
+// reconcileDelay the pause between two checks, to stay well within
+// Telegram's rate limits. Messages left over are checked on the next run.
+const reconcileBatch = 20
+
+var reconcileDelay = time.Second
+
+// reconcileCheck is when the reconciler first saw a message and last
+// checked that it still exists
+type reconcileCheck struct {
+	seen    time.Time
+	checked time.Time
+}
+
+// due checks if a message should be checked again: never checked yet, or
+// last checked more than half its age ago. A message is checked about ten
+// times over the reconcile window instead of on every run.
+func (c *reconcileCheck) due(now time.Time) bool {
+	return c.checked.IsZero() || now.Sub(c.checked) >= now.Sub(c.seen)/2
+}
+
+// handleDeleteCommand deletes the transactions of the message that /delete
+// replies to: the expense itself or the bot's message about it. For a
+// message with several lines, "/delete 3" deletes only the third line.
+func (h *EventHandler) handleDeleteCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if message.ReplyToMessage == nil {
+		msg := tgbotapi.NewMessage(chatID, "Reply to an expense, or to the bot's message about it, with /delete. For a message with several lines, add the line number: /delete 3")
+		bot.Send(msg)
+		return
+	}
+
+	transactions := h.repliedTransactions(context.Background(), message.ReplyToMessage)
+	if len(transactions) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "⚠️ That message has no transaction.")
+		bot.Send(msg)
+		return
+	}
+
+	if args := strings.TrimSpace(message.CommandArguments()); args != "" {
+		tx := lineTransaction(transactions, args)
+		if tx == nil {
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ That message has no line %s.", args))
+			bot.Send(msg)
+			return
+		}
+		transactions = []models.Transaction{*tx}
+	}
+
+	deleted := h.deleteTransactions(bot, chatID, transactions)
+	h.sendDeletionNotice(bot, chatID, deleted)
+}
+
+// repliedTransactions returns the transactions a reply points at, in line
+// order. The replied message may be the original message, by the
+// transaction ID or the source of a multi-line message, or the bot's
+// message about it.
+func (h *EventHandler) repliedTransactions(ctx context.Context, reply *tgbotapi.Message) []models.Transaction {
+	replyID := strconv.Itoa(reply.MessageID)
+
+	transactions, err := h.db.GetAllTransactions(ctx)
+	if err != nil {
+		log.Println("Failed to fetch transactions for reply:", err)
+		return nil
+	}
+	var replied []models.Transaction
+	for _, tx := range transactions {
+		if tx.ID == replyID || tx.SourceMessageID == replyID || tx.ButtonMessageID == replyID {
+			replied = append(replied, tx)
+		}
+	}
+	sort.SliceStable(replied, func(i, j int) bool {
+		return replied[i].Line < replied[j].Line
+	})
+	return replied
+}
+
+// deleteTransactions moves transactions to the trash and removes their
+// bot messages: the category message of a single transaction, its line in
+// the summary of a multi-line message, and any confirmation. It returns
+// the transactions that were deleted.
+func (h *EventHandler) deleteTransactions(bot *tgbotapi.BotAPI, chatID int64, transactions []models.Transaction) []models.Transaction {
+	ctx := context.Background()
+	var deleted []models.Transaction
+	summaries := make(map[string]string) // Source message ID to its summary message ID
+
+	for _, tx := range transactions {
+		if err := h.db.DeleteTransaction(ctx, tx.ID); err != nil {
+			log.Println("Failed to delete transaction from DB:", err)
+			continue
+		}
+		deleted = append(deleted, tx)
+
+		if tx.SourceMessageID != "" {
+			summaries[tx.SourceMessageID] = tx.ButtonMessageID
+		} else {
+			deleteMessage(bot, chatID, tx.ButtonMessageID)
+		}
+		deleteMessage(bot, chatID, tx.ConfirmationMessageID)
+	}
+
+	// A summary loses the deleted lines, or goes once none are left
+	for sourceID, buttonMsgID := range summaries {
+		if buttonMsgID != "" {
+			summaryID, _ := strconv.Atoi(buttonMsgID)
+			h.updateBatchMessage(bot, chatID, summaryID, sourceID)
+		}
+	}
+	return deleted
+}
+
+// deleteMessage deletes a message by its stored ID, if there is one
+func deleteMessage(bot *tgbotapi.BotAPI, chatID int64, messageID string) {
+	if messageID == "" {
+		return
+	}
+	id, _ := strconv.Atoi(messageID)
+	deleteMsg := tgbotapi.NewDeleteMessage(chatID, id)
+	bot.Request(deleteMsg)
+}
+
+// sendDeletionNotice tells the chat what was deleted. A single transaction
+// gets an Undo button; several can be restored with /trash. The notice
+// removes itself once Undo is no longer offered.
+func (h *EventHandler) sendDeletionNotice(bot *tgbotapi.BotAPI, chatID int64, deleted []models.Transaction) {
+	if len(deleted) == 0 {
+		return
+	}
+
+	var msg tgbotapi.MessageConfig
+	if len(deleted) == 1 {
+		content := fmt.Sprintf("🗑️ Deleted transaction: %s", utils.FormatTransactionAmount(&deleted[0], h.config.HomeCurrency))
+		msg = tgbotapi.NewMessage(chatID, content)
+		msg.ReplyMarkup = utils.BuildUndoKeyboard(deleted[0].ID)
+	} else {
+		var amounts []string
+		for i := range deleted {
+			amounts = append(amounts, utils.FormatTransactionAmount(&deleted[i], h.config.HomeCurrency))
+		}
+		content := fmt.Sprintf("🗑️ Deleted %s: %s. Restore them with /trash.", plural(len(deleted), "transaction"), strings.Join(amounts, ", "))
+		msg = tgbotapi.NewMessage(chatID, content)
+	}
+
+	sentMsg, err := bot.Send(msg)
+	if err != nil {
+		return
+	}
+	go func() {
+		time.Sleep(undoWindow)
+		deleteMsg := tgbotapi.NewDeleteMessage(chatID, sentMsg.MessageID)
+		bot.Request(deleteMsg)
+	}()
+}
+
+// Reconcile removes the transactions whose original message was deleted in
+// the last two days. Telegram doesn't tell bots about deleted messages, so
+// a message is probed by copying it to the reconcile chat; the copy is
+// deleted right away. New messages are checked often and older ones less
+// and less, a limited number per run.
+func (h *EventHandler) Reconcile(bot *tgbotapi.BotAPI) {
+	if h.config.ReconcileChatID == 0 {
+		return
+	}
+	if !h.reconcileMu.TryLock() {
+		return
+	}
+	defer h.reconcileMu.Unlock()
+
+	transactions, err := h.db.GetAllTransactions(context.Background())
+	if err != nil {
+		log.Println("Failed to fetch transactions to reconcile:", err)
+		return
+	}
+
+	// Group the recent transactions by the message they came from. Recent
+	// means sent recently; a message can log an expense from last month.
+	now := time.Now()
+	since := now.Add(-reconcileWindow).Unix()
+	bySource := make(map[int][]models.Transaction)
+	recent := make(map[int]bool)
+	for _, tx := range transactions {
+		source := sourceMessageID(&tx)
+		if source == 0 {
+			continue
+		}
+		bySource[source] = append(bySource[source], tx)
+		recent[source] = recent[source] || tx.Recorded() >= since
+	}
+
+	// Forget messages that left the window, and pick the ones due a check,
+	// least recently checked first
+	for source := range h.checks {
+		if !recent[source] {
+			delete(h.checks, source)
+		}
+	}
+	var due []int
+	for source := range recent {
+		if !recent[source] {
+			continue
+		}
+		check := h.checks[source]
+		if check == nil {
+			check = &reconcileCheck{seen: now}
+			h.checks[source] = check
+		}
+		if check.due(now) {
+			due = append(due, source)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return h.checks[due[i]].checked.Before(h.checks[due[j]].checked)
+	})
+	if len(due) > reconcileBatch {
+		due = due[:reconcileBatch]
+	}
+
+	var removed []models.Transaction
+	for i, source := range due {
+		if i > 0 {
+			time.Sleep(reconcileDelay)
+		}
+		h.checks[source].checked = time.Now()
+		if h.messageExists(bot, source) {
+			continue
+		}
+		delete(h.checks, source)
+		removed = append(removed, h.deleteTransactions(bot, h.config.ChatID, bySource[source])...)
+	}
+	if len(removed) == 0 {
+		return
+	}
+
+	var lines []string
+	for i := range removed {
+		lines = append(lines, "• "+utils.FormatTransactionAmount(&removed[i], h.config.HomeCurrency))
+	}
+	content := fmt.Sprintf("🗑️ Removed %s whose message was deleted:\n%s\n\nRestore with /trash.", plural(len(removed), "transaction"), strings.Join(lines, "\n"))
+	msg := tgbotapi.NewMessage(h.config.ChatID, content)
+	bot.Send(msg)
+}
+
+// sourceMessageID returns the ID of the chat message a transaction was
+// written in, or 0 for transactions recorded from a button
+func sourceMessageID(tx *models.Transaction) int {
+	if tx.SourceMessageID != "" {
+		id, _ := strconv.Atoi(tx.SourceMessageID)
+		return id
+	}
+	id, err := strconv.Atoi(tx.ID)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// messageExists reports whether a message is still in the chat. Only a
+// "not found" answer counts as deleted, so a failed request keeps the
+// transaction.
+func (h *EventHandler) messageExists(bot *tgbotapi.BotAPI, messageID int) bool {
+	copyMsg := tgbotapi.NewCopyMessage(h.config.ReconcileChatID, h.config.ChatID, messageID)
+	copyMsg.DisableNotification = true
+
+	copied, err := bot.CopyMessage(copyMsg)
+	if err != nil {
+		var apiErr *tgbotapi.Error
+		if errors.As(err, &apiErr) && strings.Contains(apiErr.Message, "not found") {
+			return false
+		}
+		log.Printf("Failed to check message %d: %v", messageID, err)
+		return true
+	}
+
+	deleteMsg := tgbotapi.NewDeleteMessage(h.config.ReconcileChatID, copied.MessageID)
+	bot.Request(deleteMsg)
+	return true
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconcileCheckDue(t *testing.T) {
+	seen := time.Date(2025, time.March, 14, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		checked time.Duration // After seen, or never when 0
+		now     time.Duration
+		want    bool
+	}{
+		{name: "never checked", now: 0, want: true},
+		{name: "just checked", checked: time.Minute, now: 90 * time.Second, want: false},
+		{name: "checked half its age ago", checked: time.Hour, now: 2 * time.Hour, want: true},
+		{name: "checked less than half its age ago", checked: time.Hour, now: 90 * time.Minute, want: false},
+		{name: "a day old, checked at twelve hours", checked: 12 * time.Hour, now: 24 * time.Hour, want: true},
+		{name: "a day old, checked at twenty hours", checked: 20 * time.Hour, now: 24 * time.Hour, want: false},
+	}
+
+	for _, tt := range tests {
+		check := &reconcileCheck{seen: seen}
+		if tt.checked != 0 {
+			check.checked = seen.Add(tt.checked)
+		}
+		if got := check.due(seen.Add(tt.now)); got != tt.want {
+			t.Errorf("%s: due = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
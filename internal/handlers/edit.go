@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-expense-bot/internal/database"
+	"telegram-expense-bot/internal/models"
+	"telegram-expense-bot/internal/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// editTimeout is how long the bot waits for the new value of a field
+const editTimeout = 5 * time.Minute
+
+// pendingEdit is a field of a transaction waiting for its new value from
+// the member who tapped it in the edit menu
+type pendingEdit struct {
+	TransactionID string
+	Field         string
+	PromptID      int
+	Started       time.Time
+}
+
+// editPrompts asks for the new value of each field typed in by hand
+var editPrompts = map[string]string{
+	"amount": "Send the new amount, e.g. 25.50, €20 or 12.50+8.99",
+	"date":   "Send the new date: today, yesterday, a weekday, 2025-03-14 or 14.03.2025",
+	"note":   "Send the new note, or \"-\" to remove it",
+	"payer":  "Send who paid, e.g. @alice, or \"me\"",
+	"split":  "Send the new split: equal, me, them, 60/40, alice:2,bob:1 or exact alice:12.50,bob:7.50",
+}
+
+// handleEditMenu replaces a transaction's buttons with the menu of fields
+// that can be changed
+func (h *EventHandler) handleEditMenu(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	transactionID := strings.TrimPrefix(callback.Data, "edit_")
+
+	tx, err := h.db.FindTransaction(context.Background(), transactionID)
+	if err != nil || tx == nil {
+		log.Println("Transaction not found:", err)
+		return
+	}
+
+	content := fmt.Sprintf("✏️ Editing %s", h.editLabel(tx))
+	if tx.Note != "" {
+		content += fmt.Sprintf(" (%s)", tx.Note)
+	}
+	content += "\n\nWhat do you want to change?"
+	keyboard := utils.BuildEditMenuKeyboard(tx)
+	editMsg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, content)
+	editMsg.ReplyMarkup = &keyboard
+
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to show edit menu:", err)
+	}
+}
+
+// editLabel describes a transaction in the edit menu and prompts
+func (h *EventHandler) editLabel(tx *models.Transaction) string {
+	label := utils.FormatTransactionAmount(tx, h.config.HomeCurrency)
+	switch {
+	case tx.IsSettlement():
+		return fmt.Sprintf("%s paid %s %s", tx.PaidBy(), tx.To, label)
+	case tx.Kind == models.KindIncome:
+		return label + " income"
+	case tx.Kind == models.KindRefund:
+		label = "refund of " + label
+	}
+	if tx.Category != "" {
+		label += " in " + tx.Category
+	}
+	return label
+}
+
+// handleEditField starts editing one field from the edit menu. The
+// category is picked with the usual buttons; every other field asks the
+// member for its new value.
+func (h *EventHandler) handleEditField(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	parts := strings.SplitN(callback.Data, "_", 3)
+	if len(parts) < 3 {
+		return
+	}
+
+	field := parts[1]
+	transactionID := parts[2]
+
+	tx, err := h.db.FindTransaction(context.Background(), transactionID)
+	if err != nil || tx == nil {
+		log.Println("Transaction not found:", err)
+		return
+	}
+
+	prompt, found := editPrompts[field]
+	if field == "category" || !found {
+		h.refreshTransactionMessage(bot, callback.Message.Chat.ID, tx, "Added")
+		return
+	}
+
+	content := fmt.Sprintf("✏️ %s\n%s (currently %s), or \"cancel\", as a reply to this message.", h.editLabel(tx), prompt, h.editCurrent(tx, field))
+	msg := tgbotapi.NewMessage(callback.Message.Chat.ID, content)
+	msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, Selective: true}
+
+	sentMsg, err := bot.Send(msg)
+	if err != nil {
+		log.Println("Failed to send edit prompt:", err)
+		return
+	}
+
+	h.editsMu.Lock()
+	if previous := h.edits[callback.From.ID]; previous != nil {
+		deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, previous.PromptID)
+		bot.Request(deleteMsg)
+	}
+	h.edits[callback.From.ID] = &pendingEdit{
+		TransactionID: transactionID,
+		Field:         field,
+		PromptID:      sentMsg.MessageID,
+		Started:       time.Now(),
+	}
+	h.editsMu.Unlock()
+}
+
+// editCurrent describes the current value of a field for its prompt
+func (h *EventHandler) editCurrent(tx *models.Transaction, field string) string {
+	switch field {
+	case "amount":
+		return utils.FormatTransactionAmount(tx, h.config.HomeCurrency)
+	case "date":
+		return time.Unix(tx.CreatedAt, 0).Format("2006-01-02")
+	case "note":
+		if tx.Note == "" {
+			return "none"
+		}
+		return tx.Note
+	case "payer":
+		return tx.PaidBy()
+	case "split":
+		if tx.Split == nil {
+			return "equal"
+		}
+		return utils.DescribeSplit(tx.Split)
+	}
+	return ""
+}
+
+// handleEditBack closes the edit menu, bringing back the transaction's
+// usual buttons
+func (h *EventHandler) handleEditBack(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	transactionID := strings.TrimPrefix(callback.Data, "editback_")
+
+	tx, err := h.db.FindTransaction(context.Background(), transactionID)
+	if err != nil || tx == nil {
+		log.Println("Transaction not found:", err)
+		return
+	}
+	h.refreshTransactionMessage(bot, callback.Message.Chat.ID, tx, "Added")
+}
+
+// handleEditInput takes a reply to an edit prompt as the new value of the
+// field its author is editing. Any other message, such as a new expense,
+// is left alone. It reports whether the message was such a value.
+func (h *EventHandler) handleEditInput(bot *tgbotapi.BotAPI, message *tgbotapi.Message) bool {
+	h.editsMu.Lock()
+	edit := h.edits[message.From.ID]
+	if edit != nil && time.Since(edit.Started) > editTimeout {
+		delete(h.edits, message.From.ID)
+		edit = nil
+	}
+	h.editsMu.Unlock()
+	if edit == nil || message.ReplyToMessage == nil || message.ReplyToMessage.MessageID != edit.PromptID {
+		return false
+	}
+
+	ctx := context.Background()
+	text := strings.TrimSpace(message.Text)
+	if strings.EqualFold(text, "cancel") {
+		h.finishEdit(bot, message.Chat.ID, message.From.ID, edit)
+		return true
+	}
+
+	tx, err := h.db.FindTransaction(ctx, edit.TransactionID)
+	if err != nil || tx == nil {
+		h.finishEdit(bot, message.Chat.ID, message.From.ID, edit)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "⚠️ That transaction no longer exists.")
+		bot.Send(msg)
+		return true
+	}
+
+	update, err := h.editField(ctx, bot, message, tx, edit.Field, text)
+	if err != nil {
+		// Keep waiting so the member can try again
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("⚠️ %v. Try again, or send \"cancel\".", err))
+		bot.Send(msg)
+		return true
+	}
+
+	err = h.db.UpdateTransaction(ctx, tx.ID, update)
+	if err != nil {
+		log.Println("Failed to update transaction in DB:", err)
+		msg := tgbotapi.NewMessage(message.Chat.ID, "Failed to save the change in DB.")
+		bot.Send(msg)
+		return true
+	}
+
+	h.finishEdit(bot, message.Chat.ID, message.From.ID, edit)
+	h.refreshTransactionMessage(bot, message.Chat.ID, tx, "Updated")
+	return true
+}
+
+// finishEdit stops waiting for a member's value and removes the prompt
+func (h *EventHandler) finishEdit(bot *tgbotapi.BotAPI, chatID int64, userID int64, edit *pendingEdit) {
+	h.editsMu.Lock()
+	if h.edits[userID] == edit {
+		delete(h.edits, userID)
+	}
+	h.editsMu.Unlock()
+
+	deleteMsg := tgbotapi.NewDeleteMessage(chatID, edit.PromptID)
+	bot.Request(deleteMsg)
+}
+
+// editField applies the new value of one field to tx and returns the
+// matching update
+func (h *EventHandler) editField(ctx context.Context, bot *tgbotapi.BotAPI, message *tgbotapi.Message, tx *models.Transaction, field, text string) (*database.Update, error) {
+	switch field {
+	case "amount":
+		// Read the amount as in a transaction message, so a currency,
+		// rate or arithmetic work the same way
+		parsed, err := utils.ParseTransaction(text, h.parseOptions(message))
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Currency == "" {
+			// A bare number stays in the transaction's currency
+			parsed.Currency = tx.Currency
+		}
+		currency, rate, err := h.exchangeRate(parsed, tx)
+		if err != nil {
+			return nil, err
+		}
+		tx.Amount, tx.Expression, tx.Currency, tx.Rate = parsed.Amount, parsed.Expression, currency, rate
+		update := new(database.Update).SetAmount(tx.Amount, tx.Expression).SetCurrency(tx.Currency, tx.Rate)
+		h.fitSplit(bot, message.Chat.ID, tx, update)
+		return update, nil
+
+	case "date":
+		date, ok, err := utils.ParseDate(text, message.Time())
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%q is not a date", text)
+		}
+		tx.CreatedAt = date.Unix()
+		return new(database.Update).SetCreatedAt(tx.CreatedAt), nil
+
+	case "note":
+		tx.Note = text
+		if text == "-" {
+			tx.Note = ""
+		}
+		return new(database.Update).SetNote(tx.Note), nil
+
+	case "payer":
+		payer := strings.TrimPrefix(text, "@")
+		if payer == "" || strings.ContainsAny(payer, " \t") {
+			return nil, fmt.Errorf("send a single member, e.g. @alice")
+		}
+		if strings.EqualFold(payer, "me") {
+			payer = message.From.UserName
+		} else if member, err := h.memberName(payer); err != nil {
+			return nil, err
+		} else {
+			payer = member
+		}
+		// The author is the payer by default, so store nothing for them
+		tx.Payer = payer
+		if strings.EqualFold(payer, tx.Author) {
+			tx.Payer = ""
+		}
+		return new(database.Update).SetPayer(tx.Payer), nil
+
+	case "split":
+		split, err := utils.ParseSplit(text, h.splitMembers(ctx), h.config.Members, tx.PaidBy(), tx.Amount.Abs())
+		if err != nil {
+			return nil, fmt.Errorf("can't split that way: %v", err)
+		}
+		tx.Split = split
+		return new(database.Update).SetSplit(split), nil
+
+	case "category":
+		if !tx.HasCategory() {
+			return nil, fmt.Errorf("only expenses and refunds have a category")
+		}
+		// "dining out" names a category as a whole, "dining" by its first word
+		opts := h.parseOptions(message)
+		category, _, ok := utils.MatchCategory(strings.ReplaceAll(text, " ", ""), opts)
+		if !ok {
+			category, _, ok = utils.MatchCategory(strings.Fields(text)[0], opts)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%q is not a category", text)
+		}
+		return h.setCategory(ctx, bot, message.Chat.ID, tx, category), nil
+	}
+	return nil, fmt.Errorf("%s can't be edited", field)
+}
+
+// refreshTransactionMessage redraws a transaction's bot message with its
+// usual buttons, or the summary of the message it came from
+func (h *EventHandler) refreshTransactionMessage(bot *tgbotapi.BotAPI, chatID int64, tx *models.Transaction, verb string) {
+	if tx.ButtonMessageID == "" {
+		return
+	}
+	buttonMsgID, _ := strconv.Atoi(tx.ButtonMessageID)
+
+	if tx.SourceMessageID != "" {
+		h.updateBatchMessage(bot, chatID, buttonMsgID, tx.SourceMessageID)
+		return
+	}
+	content, keyboard := h.transactionMessage(tx, verb, true)
+	editMsg := tgbotapi.NewEditMessageText(chatID, buttonMsgID, content)
+	editMsg.ReplyMarkup = &keyboard
+
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to update transaction message:", err)
+	}
+}
@@ -4,33 +4,51 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"telegram-expense-bot/internal/config"
 	"telegram-expense-bot/internal/database"
+	"telegram-expense-bot/internal/ledger"
 	"telegram-expense-bot/internal/models"
 	"telegram-expense-bot/internal/utils"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 // EventHandler handles Telegram events
 type EventHandler struct {
-	db       *database.DB
+	db       database.Store
 	config   *config.Config
 	commands *CommandHandler
+
+	editsMu sync.Mutex
+	edits   map[int64]*pendingEdit // Fields being edited, by member ID
+
+	reconcileMu sync.Mutex              // Held while reconciling
+	checks      map[int]*reconcileCheck // When the reconciler saw and last checked each message
 }
 
 // NewEventHandler creates a new event handler
-func NewEventHandler(db *database.DB, config *config.Config) *EventHandler {
+func NewEventHandler(db database.Store, config *config.Config) *EventHandler {
 	return &EventHandler{
 		db:       db,
 		config:   config,
 		commands: NewCommandHandler(db, config),
+		edits:    make(map[int64]*pendingEdit),
+		checks:   make(map[int]*reconcileCheck),
+	}
+}
+
+// HandleUpdate routes a Telegram update to the matching handler
+func (h *EventHandler) HandleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+	if update.Message != nil {
+		h.HandleMessage(bot, update.Message)
+	} else if update.EditedMessage != nil {
+		h.HandleMessage(bot, update.EditedMessage)
+	} else if update.CallbackQuery != nil {
+		h.HandleCallbackQuery(bot, update.CallbackQuery)
 	}
 }
 
@@ -63,35 +81,135 @@ func (h *EventHandler) HandleMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Mes
 		return
 	}
 
+	// A member editing a field sends its new value
+	if h.handleEditInput(bot, message) {
+		return
+	}
+
+	// A reply to a transaction's bot message sets its note
+	if message.ReplyToMessage != nil && h.handleNoteReply(bot, message) {
+		return
+	}
+
 	// Try to parse as transaction amount
 	h.handleNewTransaction(bot, message)
 }
 
+// handleNoteReply sets the note of the transaction whose bot message the
+// message replies to. A reply to the summary of a multi-line message must
+// start with the line number, e.g. "3 birthday cake". It reports whether
+// the reply was to a transaction's message.
+func (h *EventHandler) handleNoteReply(bot *tgbotapi.BotAPI, message *tgbotapi.Message) bool {
+	if strings.TrimSpace(message.Text) == "" {
+		return false
+	}
+	ctx := context.Background()
+	buttonMsgID := strconv.Itoa(message.ReplyToMessage.MessageID)
+
+	transactions, err := h.db.GetAllTransactions(ctx)
+	if err != nil {
+		log.Println("Failed to fetch transactions for note:", err)
+		return false
+	}
+	var replied []models.Transaction
+	for _, tx := range transactions {
+		if tx.ButtonMessageID == buttonMsgID {
+			replied = append(replied, tx)
+		}
+	}
+	if len(replied) == 0 {
+		return false
+	}
+
+	tx := &replied[0]
+	note := strings.TrimSpace(message.Text)
+	if tx.SourceMessageID != "" {
+		lineText, rest, _ := strings.Cut(note, " ")
+		tx = lineTransaction(replied, lineText)
+		if tx == nil {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Start the note with the line number, e.g. \"3 birthday cake\".")
+			bot.Send(msg)
+			return true
+		}
+		note = strings.TrimSpace(rest)
+	}
+
+	err = h.db.UpdateTransaction(ctx, tx.ID, new(database.Update).SetNote(note))
+	if err != nil {
+		log.Println("Failed to update note in DB:", err)
+		return true
+	}
+	tx.Note = note
+
+	if tx.SourceMessageID != "" {
+		h.updateBatchMessage(bot, message.Chat.ID, message.ReplyToMessage.MessageID, tx.SourceMessageID)
+		return true
+	}
+	// Keep a confirmation without category buttons as it was
+	markup := message.ReplyToMessage.ReplyMarkup
+	choose := markup == nil || len(markup.InlineKeyboard) > 1
+	content, keyboard := h.transactionMessage(tx, "Added", choose)
+	editMsg := tgbotapi.NewEditMessageText(message.Chat.ID, message.ReplyToMessage.MessageID, content)
+	editMsg.ReplyMarkup = &keyboard
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to update transaction message with note:", err)
+	}
+	return true
+}
+
 // handleCommand processes bot commands
 func (h *EventHandler) handleCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
 	switch message.Command() {
 	case "totals":
 		h.commands.SendTotals(bot, message.Chat.ID)
 	case "reset":
-		h.commands.ResetDatabase(bot, message.Chat.ID)
+		h.commands.RequestReset(bot, message.Chat.ID, message.From.UserName)
+	case "restore":
+		h.commands.RestoreLastReset(bot, message.Chat.ID, message.From.UserName)
 	case "help", "start":
 		h.commands.SendHelp(bot, message.Chat.ID)
+	case "settle":
+		h.commands.SendSettleUp(bot, message.Chat.ID)
 	case "history":
-		h.commands.SendTransactionHistory(bot, message.Chat.ID, 0) // 0 means all transactions
+		h.commands.SendTransactionHistory(bot, message.Chat.ID, message.Text)
 	case "compare":
 		h.commands.SendMonthlyComparison(bot, message.Chat.ID)
 	case "trends":
 		h.commands.SendSpendingTrends(bot, message.Chat.ID)
 	case "export":
 		h.commands.ExportMonthlyData(bot, message.Chat.ID, message.Text)
+	case "search":
+		h.commands.SearchTransactions(bot, message.Chat.ID, message.Text)
+	case "trash":
+		h.SendTrash(bot, message.Chat.ID)
+	case "delete", "del":
+		h.handleDeleteCommand(bot, message)
+	case "cat", "category", "note", "split", "date", "amount", "payer":
+		h.handleReplyCommand(bot, message)
 	}
 }
 
 // handleNewTransaction processes a new transaction
 func (h *EventHandler) handleNewTransaction(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
-	amount, err := utils.ValidateAmount(message.Text)
+	// A message with several lines may hold one transaction per line
+	if lines := messageLines(message.Text); len(lines) > 1 {
+		h.handleNewBatch(bot, message, lines)
+		return
+	}
+
+	parsed, err := utils.ParseTransaction(message.Text, h.parseOptions(message))
 	if err != nil {
-		// Not a valid amount, ignore
+		// Not a transaction, ignore
+		return
+	}
+	if err := h.resolveMentions(parsed); err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("⚠️ %v.", err))
+		bot.Send(msg)
+		return
+	}
+	if parsed.Kind == models.KindSettlement && strings.EqualFold(parsed.To, message.From.UserName) {
+		msg := tgbotapi.NewMessage(message.Chat.ID, "You can't pay yourself.")
+		bot.Send(msg)
 		return
 	}
 
@@ -100,11 +218,11 @@ func (h *EventHandler) handleNewTransaction(bot *tgbotapi.BotAPI, message *tgbot
 	// Create transaction ID from message ID
 	transactionID := strconv.Itoa(message.MessageID)
 
-	// Create a new transaction
-	tx := &models.Transaction{
-		ID:     transactionID,
-		Amount: amount,
-		Author: message.From.UserName,
+	tx, err := h.newTransaction(ctx, bot, message, transactionID, parsed)
+	if err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("⚠️ %v. Add the rate to the message, e.g. \"%s at 1.45\".", err, message.Text))
+		bot.Send(msg)
+		return
 	}
 
 	err = h.db.InsertTransaction(ctx, tx)
@@ -115,14 +233,119 @@ func (h *EventHandler) handleNewTransaction(bot *tgbotapi.BotAPI, message *tgbot
 		return
 	}
 
-	h.sendCategorySelection(bot, message.Chat.ID, transactionID)
+	h.sendCategorySelection(bot, message.Chat.ID, tx, tx.Category == "")
 }
 
-// sendCategorySelection sends category selection inline keyboard
-func (h *EventHandler) sendCategorySelection(bot *tgbotapi.BotAPI, chatID int64, transactionID string) {
-	keyboard := utils.BuildInlineKeyboard(h.config.Categories, transactionID)
+// newTransaction builds the transaction a parsed message describes. It
+// fails only when the exchange rate for the currency is unknown.
+func (h *EventHandler) newTransaction(ctx context.Context, bot *tgbotapi.BotAPI, message *tgbotapi.Message, id string, parsed *utils.ParsedTransaction) (*models.Transaction, error) {
+	currency, rate, err := h.exchangeRate(parsed, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &models.Transaction{
+		ID:            id,
+		Kind:          parsed.Kind,
+		Amount:        parsed.Amount,
+		Expression:    parsed.Expression,
+		Currency:      currency,
+		Rate:          rate,
+		Author:        message.From.UserName,
+		To:            parsed.To,
+		Beneficiaries: parsed.Beneficiaries,
+		Note:          parsed.Note,
+		RecordedAt:    int64(message.Date),
+	}
+	if !strings.EqualFold(parsed.Payer, message.From.UserName) {
+		tx.Payer = parsed.Payer
+	}
+	if !parsed.Date.IsZero() {
+		tx.CreatedAt = parsed.Date.Unix()
+	}
+	if parsed.Category != "" && tx.HasCategory() {
+		h.setCategory(ctx, bot, message.Chat.ID, tx, parsed.Category)
+	}
+	return tx, nil
+}
 
-	msg := tgbotapi.NewMessage(chatID, "Select a category:")
+// parseOptions returns what the transaction parser needs to know about the
+// chat, with relative dates counted from when the message was sent
+func (h *EventHandler) parseOptions(message *tgbotapi.Message) utils.ParseOptions {
+	return utils.ParseOptions{
+		Categories: h.config.Categories,
+		Aliases:    h.config.CategoryAliases,
+		Now:        message.Time(),
+	}
+}
+
+// memberName returns the member a mentioned username refers to, as spelled
+// in MEMBERS; usernames are case-insensitive. Without MEMBERS anyone may be
+// mentioned, in lower case.
+func (h *EventHandler) memberName(username string) (string, error) {
+	if len(h.config.Members) == 0 {
+		return strings.ToLower(username), nil
+	}
+	for _, member := range h.config.Members {
+		if strings.EqualFold(member, username) {
+			return member, nil
+		}
+	}
+	return "", fmt.Errorf("@%s is not a member, use one of %s", username, strings.Join(h.config.Members, ", "))
+}
+
+// resolveMentions replaces the users a parsed transaction mentions with the
+// members they refer to. Anyone mentioned takes part in equal splits, so a
+// typo would otherwise add a member who shares every expense.
+func (h *EventHandler) resolveMentions(parsed *utils.ParsedTransaction) error {
+	var err error
+	if parsed.Payer != "" {
+		if parsed.Payer, err = h.memberName(parsed.Payer); err != nil {
+			return err
+		}
+	}
+	if parsed.To != "" {
+		if parsed.To, err = h.memberName(parsed.To); err != nil {
+			return err
+		}
+	}
+	for i, user := range parsed.Beneficiaries {
+		if parsed.Beneficiaries[i], err = h.memberName(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exchangeRate resolves the currency of a parsed transaction and its rate
+// to the home currency: the one given in the message, the one already
+// stored on tx for the same currency, or the one in the rates file
+func (h *EventHandler) exchangeRate(parsed *utils.ParsedTransaction, tx *models.Transaction) (string, float64, error) {
+	currency := utils.ResolveCurrency(parsed.Currency, h.config.HomeCurrency)
+	if currency == "" {
+		return "", 0, nil
+	}
+	if parsed.Rate > 0 {
+		return currency, parsed.Rate, nil
+	}
+	if tx != nil && tx.Currency == currency && tx.Rate > 0 {
+		return currency, tx.Rate, nil
+	}
+
+	rate, err := utils.LookupRate(h.config.RatesFile, currency)
+	if err != nil {
+		return "", 0, err
+	}
+	return currency, rate, nil
+}
+
+// sendCategorySelection sends the transaction's bot message: the category
+// keyboard for expenses and refunds when choose is set, a confirmation for
+// everything else
+func (h *EventHandler) sendCategorySelection(bot *tgbotapi.BotAPI, chatID int64, tx *models.Transaction, choose bool) {
+	content, keyboard := h.transactionMessage(tx, "Added", choose)
+
+	msg := tgbotapi.NewMessage(chatID, content)
 	msg.ReplyMarkup = keyboard
 
 	sentMsg, err := bot.Send(msg)
@@ -134,12 +357,69 @@ func (h *EventHandler) sendCategorySelection(bot *tgbotapi.BotAPI, chatID int64,
 	// Store the button message ID in the database
 	ctx := context.Background()
 	buttonMsgID := strconv.Itoa(sentMsg.MessageID)
-	err = h.db.UpdateTransaction(ctx, transactionID, bson.M{"buttonMessageId": buttonMsgID})
+	err = h.db.UpdateTransaction(ctx, tx.ID, new(database.Update).SetButtonMessageID(buttonMsgID))
 	if err != nil {
 		log.Println("Failed to update buttonMessageId in DB:", err)
 	}
 }
 
+// transactionMessage builds the text and keyboard of a transaction's bot
+// message. Without choose, a categorized expense only gets a delete button,
+// since its category came with the message.
+func (h *EventHandler) transactionMessage(tx *models.Transaction, verb string, choose bool) (string, tgbotapi.InlineKeyboardMarkup) {
+	switch {
+	case tx.IsSettlement():
+		content := fmt.Sprintf("💸 %s payment: %s paid %s %s", verb, tx.PaidBy(), tx.To, utils.FormatTransactionAmount(tx, h.config.HomeCurrency))
+		return content, utils.BuildDeleteKeyboard(tx.ID)
+	case tx.Kind == models.KindIncome:
+		sharedBy := "everyone"
+		if len(tx.Beneficiaries) > 0 {
+			sharedBy = strings.Join(tx.Beneficiaries, ", ")
+		}
+		content := fmt.Sprintf("💰 %s income: %s received by %s, shared by %s", verb, utils.FormatTransactionAmount(tx, h.config.HomeCurrency), tx.PaidBy(), sharedBy)
+		return content, utils.BuildDeleteKeyboard(tx.ID)
+	}
+
+	keyboard := utils.BuildInlineKeyboard(h.config.Categories, h.splitMembers(context.Background()), tx.ID)
+	label := utils.FormatTransactionAmount(tx, h.config.HomeCurrency)
+	if tx.Kind == models.KindRefund {
+		label = "refund of " + label
+	}
+
+	splitText := ""
+	if tx.Expression != "" {
+		splitText += fmt.Sprintf("\n🧮 %s", tx.Expression)
+	}
+	if tx.PaidBy() != tx.Author {
+		splitText += fmt.Sprintf("\n💳 Paid by %s", tx.PaidBy())
+	}
+	if len(tx.Beneficiaries) > 0 {
+		splitText += fmt.Sprintf("\n👥 For: %s", strings.Join(tx.Beneficiaries, ", "))
+	}
+	if tx.Split != nil {
+		splitText += fmt.Sprintf("\n⚖️ Split: %s", utils.DescribeSplit(tx.Split))
+	}
+	if tx.Note != "" {
+		splitText += fmt.Sprintf("\n📝 %s", tx.Note)
+	}
+
+	if tx.Category == "" {
+		if tx.Kind == models.KindRefund {
+			return fmt.Sprintf("↩️ Select a category for the %s:%s", label, splitText), keyboard
+		}
+		return "Select a category:" + splitText, keyboard
+	}
+
+	content := fmt.Sprintf("✅ Added %s to %s category.%s", label, tx.Category, splitText)
+	if verb != "Added" {
+		content = fmt.Sprintf("✅ %s to %s in %s category.%s", verb, label, tx.Category, splitText)
+	}
+	if !choose {
+		return content, utils.BuildDeleteKeyboard(tx.ID)
+	}
+	return content + "\n\nTap a different category to change:", keyboard
+}
+
 // HandleCallbackQuery handles inline button callbacks
 func (h *EventHandler) HandleCallbackQuery(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 	// Only process callbacks from the configured chat
@@ -151,6 +431,34 @@ func (h *EventHandler) HandleCallbackQuery(bot *tgbotapi.BotAPI, callback *tgbot
 		h.handleCategorySelection(bot, callback)
 	} else if strings.HasPrefix(callback.Data, "delete_") {
 		h.handleTransactionDeletion(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "split_") {
+		h.handleSplitSelection(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "settle_") {
+		h.handleSettlement(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "payer_") {
+		h.handlePayerSelection(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "edit_") {
+		h.handleEditMenu(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "editset_") {
+		h.handleEditField(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "editback_") {
+		h.handleEditBack(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "undo_") {
+		h.handleUndo(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "restore_") {
+		h.handleRestore(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "reset_") {
+		h.commands.HandleResetCallback(bot, callback)
+	} else if strings.HasPrefix(callback.Data, "search_") {
+		page, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "search_"))
+		if err == nil {
+			h.commands.SendSearchPage(bot, callback.Message.Chat.ID, callback.Message.MessageID, callback.Message.Text, page)
+		}
+	} else if strings.HasPrefix(callback.Data, "history_") {
+		page, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "history_"))
+		if err == nil {
+			h.commands.SendHistoryPage(bot, callback.Message.Chat.ID, callback.Message.MessageID, callback.Message.Text, page)
+		}
 	}
 
 	// Answer the callback to remove loading state
@@ -183,17 +491,18 @@ func (h *EventHandler) handleCategorySelection(bot *tgbotapi.BotAPI, callback *t
 	}
 
 	// Update transaction category
-	err = h.db.UpdateTransaction(ctx, transactionID, bson.M{"category": newCategory})
+	err = h.db.UpdateTransaction(ctx, transactionID, h.setCategory(ctx, bot, callback.Message.Chat.ID, tx, newCategory))
 	if err != nil {
 		log.Println("Failed to update category in DB:", err)
 		return
 	}
 
 	// Update the category selection message to show confirmation and allow re-selection
-	content := fmt.Sprintf("✅ Added %.2f$ to %s category.\n\nTap a different category to change:", math.Abs(tx.Amount), newCategory)
-	
-	// Rebuild the keyboard with the updated transaction
-	keyboard := utils.BuildInlineKeyboard(h.config.Categories, transactionID)
+	if tx.SourceMessageID != "" {
+		h.updateBatchMessage(bot, callback.Message.Chat.ID, callback.Message.MessageID, tx.SourceMessageID)
+		return
+	}
+	content, keyboard := h.transactionMessage(tx, "Added", true)
 	
 	editMsg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, content)
 	editMsg.ReplyMarkup = &keyboard
@@ -204,6 +513,155 @@ func (h *EventHandler) handleCategorySelection(bot *tgbotapi.BotAPI, callback *t
 	}
 }
 
+// setCategory files tx under a category and returns the matching update. A
+// split chosen by hand is kept, otherwise the category's default rule
+// applies. A rule that doesn't fit the transaction is reported to the chat
+// and the transaction is split equally.
+func (h *EventHandler) setCategory(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, tx *models.Transaction, category string) *database.Update {
+	tx.Category = category
+	update := new(database.Update).SetCategory(category)
+	if tx.Split == nil || tx.Split.FromCategory {
+		split, err := h.categorySplit(ctx, tx, category)
+		if err != nil {
+			log.Printf("Invalid default split for %s: %v", category, err)
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ The default split for %s doesn't work: %v. Splitting it equally, fix CATEGORY_SPLITS or choose a split.", category, err))
+			bot.Send(msg)
+		}
+		tx.Split = split
+		update.SetSplit(tx.Split)
+	}
+	return update
+}
+
+// fitSplit checks that the split of tx still fits its amount after the
+// amount changed. Exact parts that no longer add up are dropped for an equal
+// split, and the chat is told, rather than being read as ratios.
+func (h *EventHandler) fitSplit(bot *tgbotapi.BotAPI, chatID int64, tx *models.Transaction, update *database.Update) {
+	err := utils.CheckSplit(tx.Split, tx.Amount.Abs())
+	if err == nil {
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ The split of %s no longer fits: %v. Splitting it equally, choose a split again if needed.", utils.FormatTransactionAmount(tx, h.config.HomeCurrency), err))
+	bot.Send(msg)
+	tx.Split = nil
+	update.SetSplit(nil)
+}
+
+// categorySplit returns the default split configured for a category, if any
+func (h *EventHandler) categorySplit(ctx context.Context, tx *models.Transaction, category string) (*models.Split, error) {
+	rule := h.config.CategorySplit(category)
+	if rule == "" {
+		return nil, nil
+	}
+
+	split, err := utils.ParseSplit(rule, h.splitMembers(ctx), h.config.Members, tx.PaidBy(), tx.Amount.Abs())
+	if err != nil {
+		return nil, fmt.Errorf("%q: %v", rule, err)
+	}
+	split.FromCategory = true
+	return split, nil
+}
+
+// splitMembers returns the members a split can name: the configured members
+// in their configured order, then anyone else with transactions
+func (h *EventHandler) splitMembers(ctx context.Context) []string {
+	members := append([]string(nil), h.config.Members...)
+	transactions, _ := h.db.GetAllTransactions(ctx)
+	for _, user := range ledger.Participants(transactions, nil) {
+		found := false
+		for _, member := range members {
+			if member == user {
+				found = true
+				break
+			}
+		}
+		if !found {
+			members = append(members, user)
+		}
+	}
+	return members
+}
+
+// handleSplitSelection processes a split button on the category keyboard
+func (h *EventHandler) handleSplitSelection(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) < 3 {
+		return
+	}
+
+	mode := parts[1]
+	transactionID := parts[2]
+
+	ctx := context.Background()
+	tx, err := h.db.FindTransaction(ctx, transactionID)
+	if err != nil || tx == nil {
+		log.Println("Transaction not found:", err)
+		return
+	}
+
+	split, err := utils.ParseSplit(mode, h.splitMembers(ctx), h.config.Members, tx.PaidBy(), tx.Amount.Abs())
+	if err != nil {
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, fmt.Sprintf("⚠️ Can't split that way: %v", err))
+		bot.Send(msg)
+		return
+	}
+
+	err = h.db.UpdateTransaction(ctx, transactionID, new(database.Update).SetSplit(split))
+	if err != nil {
+		log.Println("Failed to update split in DB:", err)
+		return
+	}
+
+	tx.Split = split
+	content, keyboard := h.transactionMessage(tx, "Added", true)
+	editMsg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, content)
+	editMsg.ReplyMarkup = &keyboard
+
+	_, err = bot.Send(editMsg)
+	if err != nil {
+		log.Println("Failed to update split selection message:", err)
+	}
+}
+
+// handlePayerSelection processes a "Paid by" button on the category keyboard
+func (h *EventHandler) handlePayerSelection(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	// Usernames may contain underscores, so they come last
+	parts := strings.SplitN(callback.Data, "_", 3)
+	if len(parts) < 3 || parts[2] == "" {
+		return
+	}
+
+	transactionID := parts[1]
+	payer := parts[2]
+
+	ctx := context.Background()
+	tx, err := h.db.FindTransaction(ctx, transactionID)
+	if err != nil || tx == nil {
+		log.Println("Transaction not found:", err)
+		return
+	}
+
+	// The author is the payer by default, so store nothing for them
+	tx.Payer = payer
+	if payer == tx.Author {
+		tx.Payer = ""
+	}
+	err = h.db.UpdateTransaction(ctx, transactionID, new(database.Update).SetPayer(tx.Payer))
+	if err != nil {
+		log.Println("Failed to update payer in DB:", err)
+		return
+	}
+
+	content, keyboard := h.transactionMessage(tx, "Added", true)
+	editMsg := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, content)
+	editMsg.ReplyMarkup = &keyboard
+
+	_, err = bot.Send(editMsg)
+	if err != nil {
+		log.Println("Failed to update payer selection message:", err)
+	}
+}
+
 // handleTransactionDeletion handles transaction deletion via callback
 func (h *EventHandler) handleTransactionDeletion(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
 	parts := strings.Split(callback.Data, "_")
@@ -223,37 +681,77 @@ func (h *EventHandler) handleTransactionDeletion(bot *tgbotapi.BotAPI, callback
 		return
 	}
 
-	// Delete from database
-	err = h.db.DeleteTransaction(ctx, transactionID)
+	// The button was on the transaction's bot message
+	tx.ButtonMessageID = strconv.Itoa(callback.Message.MessageID)
+	deleted := h.deleteTransactions(bot, callback.Message.Chat.ID, []models.Transaction{*tx})
+	h.sendDeletionNotice(bot, callback.Message.Chat.ID, deleted)
+}
+
+// handleSettlement records a suggested settle-up payment as made
+func (h *EventHandler) handleSettlement(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	parts := strings.Split(callback.Data, "_")
+	if len(parts) < 3 {
+		return
+	}
+
+	index, err1 := strconv.Atoi(parts[1])
+	cents, err2 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return
+	}
+
+	ctx := context.Background()
+	chatID := callback.Message.Chat.ID
+	totals, err := h.db.CalculateTotals(ctx)
 	if err != nil {
-		log.Println("Failed to delete transaction from DB:", err)
+		log.Println("Failed to calculate totals for settlement:", err)
 		return
 	}
 
-	// Delete the category selection message
-	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-	bot.Request(deleteMsg)
+	// Balances may have changed since the suggestions were sent; only record
+	// the payment if it is still the one on the button
+	transfers := ledger.Simplify(totals.Balances)
+	var status string
+	if index < len(transfers) && int64(transfers[index].Amount) == cents {
+		transfer := transfers[index]
+		tx := &models.Transaction{
+			ID:     "s" + callback.ID,
+			Kind:   models.KindSettlement,
+			Amount: transfer.Amount,
+			Author: transfer.From,
+			To:     transfer.To,
+		}
+		if err := h.db.InsertTransaction(ctx, tx); err != nil {
+			log.Println("Failed to record settlement:", err)
+			msg := tgbotapi.NewMessage(chatID, "Failed to save settlement in DB.")
+			bot.Send(msg)
+			return
+		}
+		status = fmt.Sprintf("✅ Recorded: %s paid %s %s", transfer.From, transfer.To, utils.FormatHome(transfer.Amount, h.config.HomeCurrency))
 
-	// Delete confirmation message if it exists
-	if tx.ConfirmationMessageID != "" {
-		confirmMsgID, _ := strconv.Atoi(tx.ConfirmationMessageID)
-		deleteConfirmMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, confirmMsgID)
-		bot.Request(deleteConfirmMsg)
+		totals, err = h.db.CalculateTotals(ctx)
+		if err != nil {
+			log.Println("Failed to recalculate totals after settlement:", err)
+			return
+		}
+		transfers = ledger.Simplify(totals.Balances)
+	} else {
+		status = "⚠️ Balances changed since these suggestions were made. Here is the updated plan."
 	}
 
-	// Send deletion confirmation
-	content := fmt.Sprintf("🗑️ Deleted transaction: %.2f$", math.Abs(tx.Amount))
-	confirmMsg := tgbotapi.NewMessage(callback.Message.Chat.ID, content)
-	sentConfirm, err := bot.Send(confirmMsg)
-	if err == nil {
-		// Auto-delete the confirmation message after 5 seconds
-		go func() {
-			time.Sleep(5 * time.Second)
-			deleteConfirm := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, sentConfirm.MessageID)
-			bot.Request(deleteConfirm)
-		}()
+	var editMsg tgbotapi.EditMessageTextConfig
+	if len(transfers) == 0 {
+		editMsg = tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, status+"\n\n🎉 Everyone is settled up!")
+	} else {
+		keyboard := utils.BuildSettleKeyboard(transfers, h.config.HomeCurrency)
+		editMsg = tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, status+"\n\n"+h.commands.settleUpText(transfers))
+		editMsg.ReplyMarkup = &keyboard
 	}
+	editMsg.ParseMode = "Markdown"
 
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to update settle up message:", err)
+	}
 }
 
 // handleEditedMessage handles message edits
@@ -263,47 +761,102 @@ func (h *EventHandler) handleEditedMessage(bot *tgbotapi.BotAPI, message *tgbota
 		return
 	}
 
-	// Parse new amount
-	newAmount, err := utils.ValidateAmount(message.Text)
-	if err != nil {
-		// Not a valid amount, ignore
-		return
-	}
-
 	ctx := context.Background()
 	transactionID := strconv.Itoa(message.MessageID)
 
-	// Find existing transaction
+	// Find existing transaction; without one the message may have held
+	// several lines
 	tx, err := h.db.FindTransaction(ctx, transactionID)
-	if err != nil || tx == nil {
-		// Transaction not found
+	if err != nil {
+		return
+	}
+	if tx == nil {
+		h.handleEditedBatch(bot, message)
+		return
+	}
+	if len(messageLines(message.Text)) > 1 {
+		// A single transaction doesn't become several
+		return
+	}
+
+	// Parse the new text, evaluating an arithmetic amount again
+	parsed, err := utils.ParseTransaction(message.Text, h.parseOptions(message))
+	if err != nil {
+		// Not a transaction, ignore
+		return
+	}
+	if err := h.resolveMentions(parsed); err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("⚠️ %v. The transaction was not changed.", err))
+		bot.Send(msg)
+		return
+	}
+	if parsed.Kind == models.KindSettlement && strings.EqualFold(parsed.To, message.From.UserName) {
 		return
 	}
 
-	// Update transaction amount
-	err = h.db.UpdateTransaction(ctx, transactionID, bson.M{"amount": newAmount})
+	update, err := h.editTransaction(ctx, bot, message.Chat.ID, tx, parsed)
+	if err != nil {
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("⚠️ %v. Add the rate to the message, e.g. \"%s at 1.45\".", err, message.Text))
+		bot.Send(msg)
+		return
+	}
+	err = h.db.UpdateTransaction(ctx, transactionID, update)
 	if err != nil {
 		log.Println("Failed to update transaction amount:", err)
 		return
 	}
 
-	// Update category selection message if it exists and has a category
+	// Update the transaction's bot message, keeping its buttons
 	if tx.ButtonMessageID != "" {
 		buttonMsgID, _ := strconv.Atoi(tx.ButtonMessageID)
-		if tx.Category != "" {
-			// Update with confirmation and keep buttons
-			content := fmt.Sprintf("✅ Updated to %.2f$ in %s category.\n\nTap a different category to change:", math.Abs(newAmount), tx.Category)
-			keyboard := utils.BuildInlineKeyboard(h.config.Categories, transactionID)
-			editMsg := tgbotapi.NewEditMessageText(message.Chat.ID, buttonMsgID, content)
-			editMsg.ReplyMarkup = &keyboard
-			bot.Send(editMsg)
-		} else {
-			// No category selected yet, just update the selection message
-			content := "Select a category:"
-			keyboard := utils.BuildInlineKeyboard(h.config.Categories, transactionID)
-			editMsg := tgbotapi.NewEditMessageText(message.Chat.ID, buttonMsgID, content)
-			editMsg.ReplyMarkup = &keyboard
-			bot.Send(editMsg)
+		content, keyboard := h.transactionMessage(tx, "Updated", parsed.Category == "")
+		editMsg := tgbotapi.NewEditMessageText(message.Chat.ID, buttonMsgID, content)
+		editMsg.ReplyMarkup = &keyboard
+		bot.Send(editMsg)
+	}
+}
+
+// editTransaction applies the edited text of a transaction's message to tx
+// and returns the matching update. It fails only when the exchange rate
+// for a new currency is unknown.
+func (h *EventHandler) editTransaction(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, tx *models.Transaction, parsed *utils.ParsedTransaction) (*database.Update, error) {
+	// Keep the rate from entry time unless the currency or rate changed
+	currency, rate, err := h.exchangeRate(parsed, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update transaction amount and kind; a payer or beneficiaries chosen
+	// earlier are kept unless the new text names them
+	update := new(database.Update).SetAmount(parsed.Amount, parsed.Expression).SetKind(parsed.Kind).SetTo(parsed.To).SetCurrency(currency, rate)
+	tx.Amount, tx.Expression, tx.Kind, tx.To = parsed.Amount, parsed.Expression, parsed.Kind, parsed.To
+	tx.Currency, tx.Rate = currency, rate
+	// A note added by reply stays unless the new text has one
+	if parsed.Note != "" {
+		tx.Note = parsed.Note
+		update.SetNote(tx.Note)
+	}
+	if !parsed.Date.IsZero() {
+		tx.CreatedAt = parsed.Date.Unix()
+		update.SetCreatedAt(tx.CreatedAt)
+	}
+	if parsed.Payer != "" {
+		tx.Payer = parsed.Payer
+		if strings.EqualFold(parsed.Payer, tx.Author) {
+			tx.Payer = ""
 		}
+		update.SetPayer(tx.Payer)
 	}
-}
\ No newline at end of file
+	if len(parsed.Beneficiaries) > 0 {
+		tx.Beneficiaries = parsed.Beneficiaries
+		update.SetBeneficiaries(tx.Beneficiaries)
+	}
+	if !tx.HasCategory() {
+		update.SetCategory("")
+		tx.Category = ""
+	} else if parsed.Category != "" && parsed.Category != tx.Category {
+		update.Merge(h.setCategory(ctx, bot, chatID, tx, parsed.Category))
+	}
+	h.fitSplit(bot, chatID, tx, update)
+	return update, nil
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"telegram-expense-bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// replyCommands maps the commands that change one field of the replied
+// transaction to that field, with an example value for their usage
+var replyCommands = map[string]struct{ Field, Example string }{
+	"cat":      {"category", "dining"},
+	"category": {"category", "dining"},
+	"note":     {"note", "birthday dinner"},
+	"split":    {"split", "70/30"},
+	"date":     {"date", "yesterday"},
+	"amount":   {"amount", "42.50"},
+	"payer":    {"payer", "@alice"},
+}
+
+// handleReplyCommand changes one field of the transaction a command
+// replies to, e.g. "/cat dining" as a reply to "25" or to the bot's message
+// about it. For a message with several lines, the value starts with the
+// line number: "/note 3 birthday cake".
+func (h *EventHandler) handleReplyCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
+	ctx := context.Background()
+	chatID := message.Chat.ID
+	command := replyCommands[message.Command()]
+	usage := fmt.Sprintf("/%s %s", message.Command(), command.Example)
+
+	if message.ReplyToMessage == nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Reply to an expense, or to the bot's message about it, with %s.", usage))
+		bot.Send(msg)
+		return
+	}
+	transactions := h.repliedTransactions(ctx, message.ReplyToMessage)
+	if len(transactions) == 0 {
+		msg := tgbotapi.NewMessage(chatID, "⚠️ That message has no transaction.")
+		bot.Send(msg)
+		return
+	}
+
+	value := strings.TrimSpace(message.CommandArguments())
+	tx := &transactions[0]
+	if len(transactions) > 1 || tx.SourceMessageID != "" {
+		lineText, rest, _ := strings.Cut(value, " ")
+		tx = lineTransaction(transactions, lineText)
+		if tx == nil {
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Start with the line number, e.g. /%s 3 %s", message.Command(), command.Example))
+			bot.Send(msg)
+			return
+		}
+		value = strings.TrimSpace(rest)
+	}
+	if value == "" {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Usage: %s", usage))
+		bot.Send(msg)
+		return
+	}
+
+	update, err := h.editField(ctx, bot, message, tx, command.Field, value)
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ %v.", err))
+		bot.Send(msg)
+		return
+	}
+	err = h.db.UpdateTransaction(ctx, tx.ID, update)
+	if err != nil {
+		log.Println("Failed to update transaction in DB:", err)
+		msg := tgbotapi.NewMessage(chatID, "Failed to save the change in DB.")
+		bot.Send(msg)
+		return
+	}
+	h.refreshTransactionMessage(bot, chatID, tx, "Updated")
+}
+
+// lineTransaction returns the transaction on a numbered line, like "3" or
+// "3.", or nil if there is none
+func lineTransaction(transactions []models.Transaction, lineText string) *models.Transaction {
+	line, err := strconv.Atoi(strings.TrimSuffix(lineText, "."))
+	if err != nil {
+		return nil
+	}
+	for i := range transactions {
+		if transactions[i].Line == line {
+			return &transactions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+	"telegram-expense-bot/internal/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// resetConfirmWindow is how long the buttons of a /reset request can be
+// used before the request expires
+const resetConfirmWindow = 60 * time.Second
+
+// resetExpiredText replaces a /reset request that was not confirmed in time
+const resetExpiredText = "⌛ Reset request expired. Send /reset again."
+
+// RequestReset asks an admin to confirm wiping every transaction. Nothing
+// is deleted until the confirmation button is tapped.
+func (h *CommandHandler) RequestReset(bot *tgbotapi.BotAPI, chatID int64, username string) {
+	if !h.config.IsAdmin(username) {
+		msg := tgbotapi.NewMessage(chatID, "⛔ Only admins can reset. Admins are listed in ADMINS.")
+		bot.Send(msg)
+		return
+	}
+
+	transactions, err := h.db.GetAllTransactions(context.Background())
+	if err != nil {
+		log.Println("Failed to fetch transactions for reset:", err)
+		msg := tgbotapi.NewMessage(chatID, "Error fetching transactions.")
+		bot.Send(msg)
+		return
+	}
+
+	content := fmt.Sprintf("⚠️ Reset deletes all %s, including the trash.\n\nA snapshot is saved first, so /restore can bring them back. Confirm within %d seconds.",
+		plural(len(transactions), "transaction"), int(resetConfirmWindow.Seconds()))
+	msg := tgbotapi.NewMessage(chatID, content)
+	msg.ReplyMarkup = utils.BuildResetKeyboard()
+
+	sentMsg, err := bot.Send(msg)
+	if err != nil {
+		log.Println("Failed to send reset request:", err)
+		return
+	}
+
+	h.resetsMu.Lock()
+	h.resets[sentMsg.MessageID] = time.Now().Add(resetConfirmWindow)
+	h.resetsMu.Unlock()
+
+	go func() {
+		time.Sleep(resetConfirmWindow)
+		if h.takeResetRequest(sentMsg.MessageID) {
+			editMsg := tgbotapi.NewEditMessageText(chatID, sentMsg.MessageID, resetExpiredText)
+			bot.Send(editMsg)
+		}
+	}()
+}
+
+// takeResetRequest removes a pending /reset request, reporting whether it
+// was still pending
+func (h *CommandHandler) takeResetRequest(messageID int) bool {
+	h.resetsMu.Lock()
+	defer h.resetsMu.Unlock()
+
+	_, pending := h.resets[messageID]
+	delete(h.resets, messageID)
+	return pending
+}
+
+// HandleResetCallback confirms or cancels a /reset request. Only an admin
+// can answer it, and only while it has not expired.
+func (h *CommandHandler) HandleResetCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	if !h.config.IsAdmin(callback.From.UserName) {
+		msg := tgbotapi.NewMessage(chatID, "⛔ Only admins can reset.")
+		bot.Send(msg)
+		return
+	}
+
+	h.resetsMu.Lock()
+	expires, pending := h.resets[messageID]
+	h.resetsMu.Unlock()
+
+	var content string
+	switch {
+	// Requests are lost on restart, so an unknown one has expired too
+	case !pending || time.Now().After(expires):
+		h.takeResetRequest(messageID)
+		content = resetExpiredText
+	case callback.Data == "reset_cancel":
+		h.takeResetRequest(messageID)
+		content = "❎ Reset cancelled."
+	case callback.Data == "reset_confirm":
+		if !h.takeResetRequest(messageID) {
+			// Another tap got there first
+			return
+		}
+		count, err := h.ResetDatabase(context.Background(), callback.From.UserName)
+		if err != nil {
+			log.Println("Failed to reset database:", err)
+			content = "Failed to reset DB. Nothing was deleted."
+		} else {
+			content = fmt.Sprintf("All %s deleted. Fresh start! /restore brings them back.", plural(count, "transaction"))
+		}
+	default:
+		return
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, content)
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to update reset request:", err)
+	}
+}
+
+// ResetDatabase saves a snapshot of every transaction, the trash included,
+// and deletes exactly the transactions in it, in one store operation, so a
+// transaction added meanwhile is never lost. It returns the number of
+// transactions that were live.
+func (h *CommandHandler) ResetDatabase(ctx context.Context, by string) (int, error) {
+	now := time.Now()
+	snapshot := &models.ResetSnapshot{
+		ID:        resetSnapshotID(now),
+		By:        by,
+		CreatedAt: now.Unix(),
+	}
+	if err := h.db.ResetTransactions(ctx, snapshot); err != nil {
+		return 0, err
+	}
+
+	live := 0
+	for _, tx := range snapshot.Transactions {
+		if tx.DeletedAt == 0 {
+			live++
+		}
+	}
+	log.Printf("Reset by %s, snapshot %s saved", by, snapshot.ID)
+	return live, nil
+}
+
+// resetSnapshotID names a snapshot by its time, with a random suffix so two
+// resets in the same second don't collide
+func resetSnapshotID(now time.Time) string {
+	return now.Format("20060102-150405") + "-" + randomID(4)
+}
+
+// RestoreLastReset brings back the transactions of the latest reset, once.
+// Transactions that already exist again are left as they are. Once the
+// periodic reset has run since, the snapshot's transactions belong to a
+// period that is archived, so they are no longer restored.
+func (h *CommandHandler) RestoreLastReset(bot *tgbotapi.BotAPI, chatID int64, username string) {
+	if !h.config.IsAdmin(username) {
+		msg := tgbotapi.NewMessage(chatID, "⛔ Only admins can restore a reset. Admins are listed in ADMINS.")
+		bot.Send(msg)
+		return
+	}
+
+	ctx := context.Background()
+	snapshot, err := h.db.GetLatestResetSnapshot(ctx)
+	if err != nil {
+		log.Println("Failed to fetch reset snapshot:", err)
+		msg := tgbotapi.NewMessage(chatID, "Error fetching the last reset.")
+		bot.Send(msg)
+		return
+	}
+	if snapshot == nil {
+		msg := tgbotapi.NewMessage(chatID, "There is no reset to restore.")
+		bot.Send(msg)
+		return
+	}
+	resetAt := time.Unix(snapshot.CreatedAt, 0).Format("Jan 2, 15:04")
+	if snapshot.RestoredAt != 0 {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("The reset of %s was already restored.", resetAt))
+		bot.Send(msg)
+		return
+	}
+	if state, err := h.db.GetResetState(ctx); err == nil && state != nil && state.CompletedAt > snapshot.CreatedAt {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ The %s was archived after the reset of %s, so it can't be restored anymore.", h.config.Cycle.Noun(), resetAt))
+		bot.Send(msg)
+		return
+	}
+
+	// Claim the snapshot first, so two /restore commands can't both run
+	claimed, err := h.db.MarkResetRestored(ctx, snapshot.ID, time.Now().Unix())
+	if err != nil {
+		log.Println("Failed to mark reset restored:", err)
+		msg := tgbotapi.NewMessage(chatID, "Error restoring the last reset.")
+		bot.Send(msg)
+		return
+	}
+	if !claimed {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("The reset of %s was already restored.", resetAt))
+		bot.Send(msg)
+		return
+	}
+
+	restored, skipped := 0, 0
+	for i := range snapshot.Transactions {
+		tx := snapshot.Transactions[i]
+		if err := h.db.InsertTransaction(ctx, &tx); err != nil {
+			skipped++
+			continue
+		}
+		if tx.DeletedAt == 0 {
+			restored++
+		}
+	}
+
+	content := fmt.Sprintf("♻️ Restored %s from the reset of %s.", plural(restored, "transaction"), resetAt)
+	if skipped > 0 {
+		content += fmt.Sprintf(" Skipped %s that already existed.", plural(skipped, "transaction"))
+	}
+	msg := tgbotapi.NewMessage(chatID, content)
+	bot.Send(msg)
+}
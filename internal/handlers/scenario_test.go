@@ -0,0 +1,776 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-expense-bot/internal/config"
+	"telegram-expense-bot/internal/database"
+	"telegram-expense-bot/internal/models"
+	"telegram-expense-bot/internal/telegramtest"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const testChatID int64 = -1001
+
+// scenario is a chat with the bot running against an in-memory store
+type scenario struct {
+	t        *testing.T
+	server   *telegramtest.Server
+	bot      *tgbotapi.BotAPI
+	db       *database.MemoryDB
+	config   *config.Config
+	events   *EventHandler
+	commands *CommandHandler
+	chat     *telegramtest.Chat
+}
+
+func newScenario(t *testing.T) *scenario {
+	t.Helper()
+	server := telegramtest.NewServer()
+	t.Cleanup(server.Close)
+	bot, err := server.NewBot()
+	if err != nil {
+		t.Fatal("Failed to create bot:", err)
+	}
+
+	cfg := &config.Config{
+		ChatID:        testChatID,
+		Members:       []string{"alice", "bob", "carol"},
+		Admins:        []string{"alice"},
+		Categories:    []string{"Groceries 🛒", "Dining Out 🍽", "Transport 🚌"},
+		HomeCurrency:  "USD",
+		Location:      time.UTC,
+		ResetSchedule: "0 9 1 * *",
+	}
+	db := database.NewMemory()
+	db.SetMembers(cfg.Members)
+
+	s := &scenario{
+		t:      t,
+		server: server,
+		bot:    bot,
+		db:     db,
+		config: cfg,
+		events: NewEventHandler(db, cfg),
+	}
+	// Commands share the handler behind the chat, with its pending requests
+	s.commands = s.events.commands
+	s.chat = telegramtest.NewChat(server, testChatID, func(update tgbotapi.Update) {
+		s.events.HandleUpdate(bot, update)
+	})
+	return s
+}
+
+// lastMessage returns the bot's latest message in the chat
+func (s *scenario) lastMessage() telegramtest.Message {
+	s.t.Helper()
+	msg, ok := s.server.LastMessage()
+	if !ok {
+		s.t.Fatal("The bot sent no message")
+	}
+	return msg
+}
+
+// findMessage returns the latest bot message containing text
+func (s *scenario) findMessage(text string) (telegramtest.Message, bool) {
+	messages := s.server.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if strings.Contains(messages[i].Text, text) {
+			return messages[i], true
+		}
+	}
+	return telegramtest.Message{}, false
+}
+
+// transaction returns the stored transaction of a user message
+func (s *scenario) transaction(msg *tgbotapi.Message) *models.Transaction {
+	s.t.Helper()
+	tx, err := s.db.FindTransaction(context.Background(), strconv.Itoa(msg.MessageID))
+	if err != nil {
+		s.t.Fatal("Failed to find transaction:", err)
+	}
+	return tx
+}
+
+// expectText fails unless text contains every part
+func expectText(t *testing.T, text string, parts ...string) {
+	t.Helper()
+	for _, part := range parts {
+		if !strings.Contains(text, part) {
+			t.Errorf("Expected %q in:\n%s", part, text)
+		}
+	}
+}
+
+func TestExpenseLifecycle(t *testing.T) {
+	s := newScenario(t)
+	ctx := context.Background()
+
+	// Everything before the reset happens in the period that just ended
+	previous := s.config.Cycle.Previous(s.config.Cycle.PeriodAt(time.Now().In(time.UTC)))
+	s.db.SetClock(func() time.Time { return previous.Start.Add(9*24*time.Hour + 12*time.Hour) })
+
+	// An amount asks for a category
+	groceries := s.chat.Send("alice", "30")
+	prompt := s.lastMessage()
+	expectText(t, prompt.Text, "Select a category:")
+	if _, err := s.chat.PressButton("alice", prompt.ID, "Groceries 🛒"); err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, s.lastMessage().Text, "✅ Added 30.00$ to Groceries 🛒 category.")
+	if tx := s.transaction(groceries); tx == nil || tx.Category != "Groceries 🛒" || tx.Amount != 3000 {
+		t.Fatalf("Expected 30.00 in Groceries, got %+v", tx)
+	}
+
+	// A category in the message needs no button
+	s.chat.Send("bob", "45.50 dining out")
+	expectText(t, s.lastMessage().Text, "✅ Added 45.50$ to Dining Out 🍽 category.")
+
+	// Editing the message changes the amount and keeps the category
+	s.chat.Edit(groceries, "36")
+	tx := s.transaction(groceries)
+	if tx == nil || tx.Amount != 3600 || tx.Category != "Groceries 🛒" {
+		t.Fatalf("Expected 36.00 in Groceries after the edit, got %+v", tx)
+	}
+	edited, ok := s.server.Message(prompt.ID)
+	if !ok {
+		t.Fatal("The category message is gone after the edit")
+	}
+	expectText(t, edited.Text, "✅ Updated to 36.00$ in Groceries 🛒 category.")
+
+	// /delete in reply to an expense moves it to the trash
+	mistake := s.chat.Send("carol", "12")
+	mistakePrompt := s.lastMessage()
+	s.chat.Reply("bob", mistake.MessageID, "/delete")
+	if tx := s.transaction(mistake); tx != nil {
+		t.Fatalf("Expected the transaction to be deleted, got %+v", tx)
+	}
+	if _, ok := s.server.Message(mistakePrompt.ID); ok {
+		t.Error("Expected the deleted transaction's message to be removed")
+	}
+	expectText(t, s.lastMessage().Text, "🗑️ Deleted transaction: 12.00$")
+
+	// /totals shares both expenses between the three members
+	s.chat.Send("bob", "/totals")
+	expectText(t, s.lastMessage().Text,
+		"bob is owed **18.33$**",
+		"alice is owed **8.83$**",
+		"carol owes **27.16$**",
+		"alice: contributed 36.00$, share 27.17$",
+		"Groceries 🛒 **36.00$**",
+		"Dining Out 🍽 **45.50$**",
+	)
+
+	// A transaction from the running period carries over the reset
+	s.db.SetClock(time.Now)
+	fare := s.chat.Send("carol", "8 transport")
+
+	s.commands.MonthlyReset(s.bot)
+
+	report, ok := s.findMessage("EXPENSE REPORT")
+	if !ok {
+		t.Fatal("The reset sent no report")
+	}
+	expectText(t, report.Text,
+		strings.ToUpper(previous.Label())+" EXPENSE REPORT",
+		"Expenses: 2",
+		"Total spent: **81.50$**",
+		"✅ 2 transactions archived",
+		"Carried over 1 transaction",
+	)
+	if export := s.lastMessage(); export.Document == "" {
+		t.Error("Expected the archive to be exported as CSV after the report")
+	}
+
+	archive, err := s.db.GetMonthlyArchive(ctx, previous.ID())
+	if err != nil || archive == nil {
+		t.Fatalf("Expected an archive of %s, got %v, %v", previous.ID(), archive, err)
+	}
+	if len(archive.Transactions) != 2 || archive.TotalSpent != 8150 {
+		t.Errorf("Expected 2 transactions and 81.50 archived, got %d and %s", len(archive.Transactions), archive.TotalSpent)
+	}
+	if archive.PeriodStart != previous.Start.Unix() || archive.PeriodEnd != previous.End.Unix() {
+		t.Errorf("Expected the archive to cover %s", previous.Label())
+	}
+
+	live, _ := s.db.GetAllTransactions(ctx)
+	if len(live) != 1 || live[0].ID != strconv.Itoa(fare.MessageID) {
+		t.Errorf("Expected only the new transaction to carry over, got %+v", live)
+	}
+	state, _ := s.db.GetResetState(ctx)
+	if state == nil || state.PeriodEnd != previous.End.Unix() {
+		t.Errorf("Expected the reset of %s to be recorded, got %+v", previous.ID(), state)
+	}
+
+	// The next run has nothing to do
+	s.server.ResetCalls()
+	s.commands.MonthlyReset(s.bot)
+	if calls := s.server.Calls("sendMessage"); len(calls) != 0 {
+		t.Errorf("Expected no messages from a second reset, got %v", calls)
+	}
+}
+
+func TestBatchMessage(t *testing.T) {
+	s := newScenario(t)
+
+	source := s.chat.Send("alice", "Costco run:\n20 groceries\n15.50 transport\nhello")
+	summary := s.lastMessage()
+	expectText(t, summary.Text,
+		"🧾 Added 2 transactions:",
+		"2. 20.00$, Groceries 🛒",
+		"3. 15.50$, Transport 🚌",
+		"Total spent: 35.50$",
+	)
+
+	// Lines keep their position in the message, the heading included.
+	// Removing a line deletes its transaction
+	s.chat.Edit(source, "Costco run:\n20 groceries")
+	updated, ok := s.server.Message(summary.ID)
+	if !ok {
+		t.Fatal("The summary is gone after the edit")
+	}
+	expectText(t, updated.Text, "🧾 Added 1 transaction:", "2. 20.00$, Groceries 🛒")
+
+	totals, err := s.db.CalculateTotals(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.TotalSpent != 2000 {
+		t.Errorf("Expected 20.00 spent, got %s", totals.TotalSpent)
+	}
+}
+
+func TestDeleteButton(t *testing.T) {
+	s := newScenario(t)
+
+	expense := s.chat.Send("alice", "20 groceries")
+	prompt := s.lastMessage()
+	if _, err := s.chat.PressButton("alice", prompt.ID, "🗑️ Delete Transaction"); err != nil {
+		t.Fatal(err)
+	}
+	if tx := s.transaction(expense); tx != nil {
+		t.Fatalf("Expected the transaction to be deleted, got %+v", tx)
+	}
+	if _, ok := s.server.Message(prompt.ID); ok {
+		t.Error("Expected the transaction's message to be removed")
+	}
+	expectText(t, s.lastMessage().Text, "🗑️ Deleted transaction: 20.00$")
+
+	s.chat.Send("bob", "/totals")
+	expectText(t, s.lastMessage().Text, "❌ No transactions found")
+}
+
+func TestUndoAndTrash(t *testing.T) {
+	s := newScenario(t)
+	s.config.TrashRetention = 30 * 24 * time.Hour
+
+	// Undo on the deletion notice brings the transaction and its message back
+	groceries := s.chat.Send("alice", "20 groceries")
+	if _, err := s.chat.PressButton("alice", s.lastMessage().ID, "🗑️ Delete Transaction"); err != nil {
+		t.Fatal(err)
+	}
+	notice := s.lastMessage()
+	if _, err := s.chat.PressButton("alice", notice.ID, "↩️ Undo"); err != nil {
+		t.Fatal(err)
+	}
+	if tx := s.transaction(groceries); tx == nil || tx.Amount != 2000 {
+		t.Fatalf("Expected the groceries to be back, got %+v", tx)
+	}
+	if _, ok := s.server.Message(notice.ID); ok {
+		t.Error("Expected the deletion notice to be removed")
+	}
+	expectText(t, s.lastMessage().Text, "✅ Added 20.00$ to Groceries 🛒 category.")
+
+	// /trash lists what can still be restored, purging the expired first
+	transport := s.chat.Send("bob", "12 transport")
+	s.chat.Reply("alice", groceries.MessageID, "/delete")
+	s.chat.Reply("bob", transport.MessageID, "/delete")
+	expired := time.Now().Add(-s.config.TrashRetention - time.Hour).Unix()
+	if err := s.db.InsertTransaction(context.Background(), &models.Transaction{ID: "expired", Amount: 9900, Author: "carol", DeletedAt: expired}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.chat.Send("carol", "/trash")
+	trash := s.lastMessage()
+	expectText(t, trash.Text, "🗑️ Trash: 2 deleted transactions, kept for 30 days", "12.00$", "20.00$")
+	if strings.Contains(trash.Text, "99.00$") {
+		t.Errorf("Expected the expired transaction to be purged from:\n%s", trash.Text)
+	}
+
+	// The groceries were deleted first, so they are listed second
+	if _, err := s.chat.PressButton("carol", trash.ID, "♻️ 2"); err != nil {
+		t.Fatal(err)
+	}
+	if tx := s.transaction(groceries); tx == nil {
+		t.Fatal("Expected the groceries to be restored from the trash")
+	}
+	updated, _ := s.server.Message(trash.ID)
+	expectText(t, updated.Text, "🗑️ Trash: 1 deleted transaction")
+	if _, err := s.chat.PressButton("carol", trash.ID, "♻️ 1"); err != nil {
+		t.Fatal(err)
+	}
+	if tx := s.transaction(transport); tx == nil || tx.Amount != 1200 {
+		t.Fatalf("Expected the transport to be restored, got %+v", tx)
+	}
+	updated, _ = s.server.Message(trash.ID)
+	expectText(t, updated.Text, "🗑️ The trash is empty.")
+}
+
+func TestChangeCategory(t *testing.T) {
+	s := newScenario(t)
+
+	expense := s.chat.Send("alice", "30")
+	prompt := s.lastMessage()
+	for _, category := range []string{"Groceries 🛒", "Dining Out 🍽"} {
+		if _, err := s.chat.PressButton("alice", prompt.ID, category); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectText(t, s.lastMessage().Text, "✅ Added 30.00$ to Dining Out 🍽 category.", "Tap a different category to change:")
+
+	// A category in the edited text replaces the one chosen by button
+	s.chat.Edit(expense, "36 transport")
+	if tx := s.transaction(expense); tx == nil || tx.Category != "Transport 🚌" || tx.Amount != 3600 {
+		t.Fatalf("Expected 36.00 in Transport after the edit, got %+v", tx)
+	}
+	edited, _ := s.server.Message(prompt.ID)
+	expectText(t, edited.Text, "✅ Updated to 36.00$ in Transport 🚌 category.")
+
+	s.chat.Send("alice", "/totals")
+	totals := s.lastMessage().Text
+	expectText(t, totals, "Transport 🚌 **36.00$**")
+	if strings.Contains(totals, "Dining Out") {
+		t.Errorf("Expected no Dining Out spending left in:\n%s", totals)
+	}
+}
+
+func TestRefundAndIncome(t *testing.T) {
+	s := newScenario(t)
+
+	s.chat.Send("alice", "90 groceries")
+	s.chat.Send("alice", "refund 30 groceries")
+	expectText(t, s.lastMessage().Text, "✅ Added refund of 30.00$ to Groceries 🛒 category.")
+	s.chat.Send("bob", "income 30")
+	expectText(t, s.lastMessage().Text, "💰 Added income: 30.00$ received by bob, shared by everyone")
+
+	// The refund leaves 60 shared; bob holds 30 of everyone's income
+	s.chat.Send("carol", "/totals")
+	expectText(t, s.lastMessage().Text,
+		"alice is owed **50.00$**",
+		"bob owes **40.00$**",
+		"carol owes **10.00$**",
+		"Groceries 🛒 **60.00$**",
+	)
+}
+
+func TestSettleUp(t *testing.T) {
+	s := newScenario(t)
+
+	s.chat.Send("alice", "30 groceries")
+	s.chat.Send("bob", "/settle")
+	plan := s.lastMessage()
+	expectText(t, plan.Text, "1. bob pays **10.00$** to alice", "2. carol pays **10.00$** to alice")
+
+	if _, err := s.chat.PressButton("bob", plan.ID, "✅ bob paid alice 10.00$"); err != nil {
+		t.Fatal(err)
+	}
+	plan, _ = s.server.Message(plan.ID)
+	expectText(t, plan.Text, "✅ Recorded: bob paid alice 10.00$", "1. carol pays **10.00$** to alice")
+
+	if _, err := s.chat.PressButton("carol", plan.ID, "✅ carol paid alice 10.00$"); err != nil {
+		t.Fatal(err)
+	}
+	plan, _ = s.server.Message(plan.ID)
+	expectText(t, plan.Text, "🎉 Everyone is settled up!")
+
+	s.chat.Send("alice", "/settle")
+	expectText(t, s.lastMessage().Text, "✅ All settled! Nobody owes anything.")
+}
+
+func TestOtherChatsAreIgnored(t *testing.T) {
+	s := newScenario(t)
+	other := telegramtest.NewChat(s.server, testChatID-1, func(update tgbotapi.Update) {
+		s.events.HandleUpdate(s.bot, update)
+	})
+
+	msg := other.Send("alice", "25 groceries")
+	other.Send("alice", "/totals")
+	if calls := s.server.Calls("sendMessage"); len(calls) != 0 {
+		t.Errorf("Expected no replies in another chat, got %v", calls)
+	}
+	if tx := s.transaction(msg); tx != nil {
+		t.Errorf("Expected no transaction from another chat, got %+v", tx)
+	}
+}
+
+func TestResetWithoutTransactions(t *testing.T) {
+	s := newScenario(t)
+	previous := s.config.Cycle.Previous(s.config.Cycle.PeriodAt(time.Now().In(time.UTC)))
+
+	s.commands.MonthlyReset(s.bot)
+	expectText(t, s.lastMessage().Text,
+		strings.ToUpper(previous.Label())+" EXPENSE REPORT",
+		"❌ No transactions this month",
+	)
+	if archive, _ := s.db.GetMonthlyArchive(context.Background(), previous.ID()); archive != nil {
+		t.Errorf("Expected no archive of an empty period, got %+v", archive)
+	}
+	if state, _ := s.db.GetResetState(context.Background()); state == nil || state.PeriodEnd != previous.End.Unix() {
+		t.Errorf("Expected the reset of %s to be recorded, got %+v", previous.ID(), state)
+	}
+}
+
+func TestMentionsMustBeMembers(t *testing.T) {
+	s := newScenario(t)
+
+	// Mentions are matched to MEMBERS whatever their case
+	payment := s.chat.Send("alice", "paid @Bob 10")
+	if tx := s.transaction(payment); tx == nil || tx.To != "bob" {
+		t.Fatalf("Expected a payment to bob, got %+v", tx)
+	}
+	shared := s.chat.Send("alice", "30 @CAROL paid for @alice @Bob")
+	if tx := s.transaction(shared); tx == nil || tx.Payer != "carol" || strings.Join(tx.Beneficiaries, ",") != "alice,bob" {
+		t.Fatalf("Expected carol to pay for alice and bob, got %+v", tx)
+	}
+
+	// A typo is rejected instead of adding someone to every split
+	typo := s.chat.Send("alice", "25 @bbo paid")
+	expectText(t, s.lastMessage().Text, "⚠️ @bbo is not a member, use one of alice, bob, carol.")
+	if tx := s.transaction(typo); tx != nil {
+		t.Errorf("Expected no transaction for an unknown payer, got %+v", tx)
+	}
+	s.chat.Send("alice", "paid @dave 5")
+	expectText(t, s.lastMessage().Text, "⚠️ @dave is not a member")
+
+	// Editing in an unknown member keeps the transaction as it was
+	s.chat.Edit(shared, "30 @zed paid")
+	expectText(t, s.lastMessage().Text, "⚠️ @zed is not a member", "The transaction was not changed.")
+	if tx := s.transaction(shared); tx == nil || tx.Payer != "carol" {
+		t.Errorf("Expected the payer to stay carol, got %+v", tx)
+	}
+
+	// So does naming one with /payer
+	s.chat.Reply("alice", shared.MessageID, "/payer @zed")
+	expectText(t, s.lastMessage().Text, "⚠️ @zed is not a member")
+	s.chat.Reply("alice", shared.MessageID, "/payer @BOB")
+	if tx := s.transaction(shared); tx == nil || tx.Payer != "bob" {
+		t.Errorf("Expected the payer to be bob, got %+v", tx)
+	}
+
+	// A line of a multi-line message is reported and the rest added
+	s.chat.Send("bob", "10 groceries\n20 @dave paid")
+	problems, ok := s.findMessage("Some lines were not added")
+	if !ok {
+		t.Fatal("Expected the unknown member's line to be reported")
+	}
+	expectText(t, problems.Text, "Line 2: @dave is not a member")
+
+	totals, err := s.db.CalculateTotals(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range totals.Balances {
+		switch b.User {
+		case "alice", "bob", "carol":
+		default:
+			t.Errorf("Expected only members in the balances, got %s", b.User)
+		}
+	}
+}
+
+func TestAmountChangeRefitsExactSplit(t *testing.T) {
+	s := newScenario(t)
+
+	exact := func(msg *tgbotapi.Message) {
+		t.Helper()
+		s.chat.Reply("alice", msg.MessageID, "/split exact alice:12.50,bob:7.50")
+		if tx := s.transaction(msg); tx == nil || tx.Split == nil || tx.Split.Mode != models.SplitExact {
+			t.Fatalf("Expected an exact split, got %+v", tx)
+		}
+	}
+	equal := func(msg *tgbotapi.Message, amount models.Money) {
+		t.Helper()
+		expectText(t, s.lastMessage().Text, "no longer fits: amounts add up to 20.00, not")
+		if tx := s.transaction(msg); tx == nil || tx.Amount != amount || !tx.Split.IsEqual() {
+			t.Errorf("Expected %s split equally, got %+v", amount, tx)
+		}
+	}
+
+	// The /amount reply command
+	reply := s.chat.Send("alice", "20 groceries")
+	exact(reply)
+	s.chat.Reply("alice", reply.MessageID, "/amount 30")
+	equal(reply, 3000)
+
+	// Editing the message
+	edited := s.chat.Send("alice", "20 groceries")
+	exact(edited)
+	s.chat.Edit(edited, "25 groceries")
+	equal(edited, 2500)
+
+	// An amount that still adds up keeps the split
+	kept := s.chat.Send("alice", "20 groceries")
+	exact(kept)
+	s.chat.Reply("alice", kept.MessageID, "/amount 12.50+7.50")
+	if tx := s.transaction(kept); tx == nil || tx.Split == nil || tx.Split.Mode != models.SplitExact {
+		t.Errorf("Expected the exact split to stay, got %+v", tx)
+	}
+}
+
+func TestResetMovesLegacyArchive(t *testing.T) {
+	s := newScenario(t)
+	ctx := context.Background()
+
+	previous := s.config.Cycle.Previous(s.config.Cycle.PeriodAt(time.Now().In(time.UTC)))
+	before := s.config.Cycle.Previous(previous)
+
+	// Archives used to be keyed by the month their reset ran in and hold
+	// the month before, so this one has the ID of the period being reset
+	legacy := models.MonthlyArchive{
+		ID:                previous.ID(),
+		Year:              previous.Start.Year(),
+		Month:             int(previous.Start.Month()),
+		MonthName:         previous.Start.Format("January"),
+		TotalSpent:        2000,
+		TotalTransactions: 1,
+		Transactions: []models.Transaction{
+			{ID: "legacy", Kind: models.KindExpense, Amount: 2000, Author: "bob", CreatedAt: before.Start.Add(48 * time.Hour).Unix()},
+		},
+	}
+	if err := s.db.MoveArchive(ctx, "", &legacy); err != nil {
+		t.Fatal("Failed to seed the legacy archive:", err)
+	}
+
+	s.db.SetClock(func() time.Time { return previous.Start.Add(36 * time.Hour) })
+	s.chat.Send("alice", "30 groceries")
+	s.db.SetClock(time.Now)
+	s.commands.MonthlyReset(s.bot)
+
+	archive, err := s.db.GetMonthlyArchive(ctx, previous.ID())
+	if err != nil || archive == nil {
+		t.Fatalf("Expected an archive of %s, got %v, %v", previous.ID(), archive, err)
+	}
+	if len(archive.Transactions) != 1 || archive.TotalSpent != 3000 || archive.PeriodStart != previous.Start.Unix() {
+		t.Errorf("Expected only %s's 30.00 in its archive, got %+v", previous.Label(), archive)
+	}
+
+	moved, err := s.db.GetMonthlyArchive(ctx, before.ID())
+	if err != nil || moved == nil {
+		t.Fatalf("Expected the legacy archive under %s, got %v, %v", before.ID(), moved, err)
+	}
+	if len(moved.Transactions) != 1 || moved.Transactions[0].ID != "legacy" {
+		t.Errorf("Expected the legacy transaction to stay in its archive, got %+v", moved.Transactions)
+	}
+	if moved.PeriodStart != before.Start.Unix() || moved.PeriodEnd != before.End.Unix() || moved.PeriodLabel() != before.Label() {
+		t.Errorf("Expected the legacy archive to cover %s, got %s", before.Label(), moved.PeriodLabel())
+	}
+}
+
+func TestResetNumbersLegacyArchiveWhenTaken(t *testing.T) {
+	s := newScenario(t)
+	ctx := context.Background()
+
+	previous := s.config.Cycle.Previous(s.config.Cycle.PeriodAt(time.Now().In(time.UTC)))
+	before := s.config.Cycle.Previous(previous)
+
+	// The month before already has an archive of its own
+	taken := models.MonthlyArchive{ID: before.ID(), PeriodStart: before.Start.Unix(), PeriodEnd: before.End.Unix()}
+	legacy := models.MonthlyArchive{ID: previous.ID(), Year: previous.Start.Year(), Month: int(previous.Start.Month())}
+	for _, archive := range []*models.MonthlyArchive{&taken, &legacy} {
+		if err := s.db.MoveArchive(ctx, "", archive); err != nil {
+			t.Fatal("Failed to seed the archives:", err)
+		}
+	}
+
+	s.db.SetClock(func() time.Time { return previous.Start.Add(36 * time.Hour) })
+	s.chat.Send("alice", "30 groceries")
+	s.db.SetClock(time.Now)
+	s.commands.MonthlyReset(s.bot)
+
+	archives, _ := s.db.GetAllArchives(ctx)
+	ids := make(map[string]bool)
+	for _, archive := range archives {
+		ids[archive.ID] = true
+	}
+	for _, id := range []string{previous.ID(), before.ID(), before.ID() + "-2"} {
+		if !ids[id] {
+			t.Errorf("Expected archive %s, got %v", id, ids)
+		}
+	}
+	if len(archives) != 3 {
+		t.Errorf("Expected 3 archives, got %d", len(archives))
+	}
+}
+
+func TestResetAndRestore(t *testing.T) {
+	s := newScenario(t)
+	ctx := context.Background()
+
+	// Only admins can ask for a reset or answer one
+	s.chat.Send("bob", "/reset")
+	expectText(t, s.lastMessage().Text, "⛔ Only admins can reset.")
+
+	groceries := s.chat.Send("alice", "30 groceries")
+	s.chat.Send("bob", "12 transport")
+
+	s.chat.Send("alice", "/reset")
+	prompt := s.lastMessage()
+	expectText(t, prompt.Text, "Reset deletes all 2 transactions")
+	if _, err := s.chat.PressButton("bob", prompt.ID, "✅ Yes, reset"); err != nil {
+		t.Fatal(err)
+	}
+	expectText(t, s.lastMessage().Text, "⛔ Only admins can reset.")
+	if live, _ := s.db.GetAllTransactions(ctx); len(live) != 2 {
+		t.Fatalf("Expected a non-admin's tap to delete nothing, got %d left", len(live))
+	}
+
+	if _, err := s.chat.PressButton("alice", prompt.ID, "✅ Yes, reset"); err != nil {
+		t.Fatal(err)
+	}
+	confirmed, _ := s.server.Message(prompt.ID)
+	expectText(t, confirmed.Text, "All 2 transactions deleted.")
+	if live, _ := s.db.GetAllTransactions(ctx); len(live) != 0 {
+		t.Fatalf("Expected no transactions after the reset, got %d", len(live))
+	}
+
+	// /restore brings them back once, and only for admins
+	s.chat.Send("bob", "/restore")
+	expectText(t, s.lastMessage().Text, "⛔ Only admins can restore a reset.")
+	s.chat.Send("alice", "/restore")
+	expectText(t, s.lastMessage().Text, "♻️ Restored 2 transactions")
+	if tx := s.transaction(groceries); tx == nil || tx.Amount != 3000 {
+		t.Fatalf("Expected the groceries to be back, got %+v", tx)
+	}
+	s.chat.Reply("alice", groceries.MessageID, "/delete")
+	s.chat.Send("alice", "/restore")
+	expectText(t, s.lastMessage().Text, "was already restored.")
+	if tx := s.transaction(groceries); tx != nil {
+		t.Errorf("Expected a second /restore to bring nothing back, got %+v", tx)
+	}
+}
+
+func TestResetRequestExpires(t *testing.T) {
+	s := newScenario(t)
+	ctx := context.Background()
+	s.chat.Send("alice", "30 groceries")
+
+	s.chat.Send("alice", "/reset")
+	cancelled := s.lastMessage()
+	if _, err := s.chat.PressButton("alice", cancelled.ID, "❌ Cancel"); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := s.server.Message(cancelled.ID)
+	expectText(t, msg.Text, "❎ Reset cancelled.")
+
+	// A request past its window can't be confirmed
+	s.chat.Send("alice", "/reset")
+	expired := s.lastMessage()
+	s.commands.resetsMu.Lock()
+	s.commands.resets[expired.ID] = time.Now().Add(-time.Second)
+	s.commands.resetsMu.Unlock()
+	if _, err := s.chat.PressButton("alice", expired.ID, "✅ Yes, reset"); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ = s.server.Message(expired.ID)
+	expectText(t, msg.Text, resetExpiredText)
+
+	if live, _ := s.db.GetAllTransactions(ctx); len(live) != 1 {
+		t.Errorf("Expected the transaction to survive, got %d", len(live))
+	}
+}
+
+func TestRestoreAfterPeriodicReset(t *testing.T) {
+	s := newScenario(t)
+	ctx := context.Background()
+	s.chat.Send("alice", "30 groceries")
+
+	s.chat.Send("alice", "/reset")
+	if _, err := s.chat.PressButton("alice", s.lastMessage().ID, "✅ Yes, reset"); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := s.db.GetLatestResetSnapshot(ctx)
+	if err != nil || snapshot == nil {
+		t.Fatal("Expected a reset snapshot:", err)
+	}
+
+	// The periodic reset archived a period after the snapshot was taken
+	period := s.config.Cycle.PeriodAt(time.Now())
+	state := models.NewResetState(period, snapshot.CreatedAt+60)
+	if err := s.db.SaveResetState(ctx, state); err != nil {
+		t.Fatal(err)
+	}
+
+	s.chat.Send("alice", "/restore")
+	expectText(t, s.lastMessage().Text, "can't be restored anymore")
+	if live, _ := s.db.GetAllTransactions(ctx); len(live) != 0 {
+		t.Errorf("Expected nothing restored, got %d", len(live))
+	}
+}
+
+// noReconcileDelay lets the reconciler check messages back to back
+func noReconcileDelay(t *testing.T) {
+	delay := reconcileDelay
+	reconcileDelay = 0
+	t.Cleanup(func() { reconcileDelay = delay })
+}
+
+func TestReconcileBackdatedTransaction(t *testing.T) {
+	s := newScenario(t)
+	s.config.ReconcileChatID = -1002
+	noReconcileDelay(t)
+
+	// Logged today for a date last week, then the message is deleted
+	lastWeek := time.Now().AddDate(0, 0, -7).Format("2006-01-02")
+	backdated := s.chat.Send("alice", "30 groceries "+lastWeek)
+	kept := s.chat.Send("bob", "12 transport")
+	if tx := s.transaction(backdated); tx == nil || time.Since(time.Unix(tx.CreatedAt, 0)) < 6*24*time.Hour {
+		t.Fatalf("Expected a transaction dated last week, got %+v", tx)
+	}
+	s.chat.Delete(backdated)
+
+	s.events.Reconcile(s.bot)
+	expectText(t, s.lastMessage().Text, "Removed 1 transaction whose message was deleted", "30.00$")
+	if tx := s.transaction(backdated); tx != nil {
+		t.Errorf("Expected the backdated transaction to be removed, got %+v", tx)
+	}
+	if tx := s.transaction(kept); tx == nil {
+		t.Error("Expected the transaction whose message is still there to stay")
+	}
+}
+
+func TestReconcileChecksABatchPerRun(t *testing.T) {
+	s := newScenario(t)
+	s.config.ReconcileChatID = -1002
+	noReconcileDelay(t)
+
+	total := reconcileBatch + 5
+	for i := 0; i < total; i++ {
+		s.chat.Delete(s.chat.Send("alice", fmt.Sprintf("%d groceries", i+1)))
+	}
+
+	// Each run probes at most a batch of messages; the rest wait their turn
+	s.server.ResetCalls()
+	s.events.Reconcile(s.bot)
+	if calls := s.server.Calls("copyMessage"); len(calls) != reconcileBatch {
+		t.Errorf("Expected %d messages checked in one run, got %d", reconcileBatch, len(calls))
+	}
+	live, _ := s.db.GetAllTransactions(context.Background())
+	if len(live) != total-reconcileBatch {
+		t.Fatalf("Expected %d transactions left after one run, got %d", total-reconcileBatch, len(live))
+	}
+
+	s.server.ResetCalls()
+	s.events.Reconcile(s.bot)
+	if calls := s.server.Calls("copyMessage"); len(calls) != total-reconcileBatch {
+		t.Errorf("Expected the %d left over checked next, got %d", total-reconcileBatch, len(calls))
+	}
+	if live, _ := s.db.GetAllTransactions(context.Background()); len(live) != 0 {
+		t.Errorf("Expected every transaction removed after two runs, got %d", len(live))
+	}
+}
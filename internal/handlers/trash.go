@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+	"telegram-expense-bot/internal/utils"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// undoWindow is how long a deletion notice, with its Undo button, stays in
+// the chat. After that the transaction can still be restored with /trash.
+const undoWindow = 30 * time.Second
+
+// trashListSize caps the deleted transactions listed by /trash
+const trashListSize = 10
+
+// SendTrash lists the most recently deleted transactions with buttons to
+// restore them
+func (h *EventHandler) SendTrash(bot *tgbotapi.BotAPI, chatID int64) {
+	content, keyboard := h.trashMessage()
+	msg := tgbotapi.NewMessage(chatID, content)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	bot.Send(msg)
+}
+
+// trashMessage builds the /trash list, purging expired items first so
+// only restorable ones are shown
+func (h *EventHandler) trashMessage() (string, *tgbotapi.InlineKeyboardMarkup) {
+	h.commands.PurgeTrash()
+
+	deleted, err := h.db.GetDeletedTransactions(context.Background())
+	if err != nil {
+		log.Println("Failed to fetch the trash:", err)
+		return "Error fetching deleted transactions.", nil
+	}
+	if len(deleted) == 0 {
+		return "🗑️ The trash is empty.", nil
+	}
+
+	days := int(h.config.TrashRetention.Hours() / 24)
+	content := fmt.Sprintf("🗑️ Trash: %s, kept for %s\n\n", plural(len(deleted), "deleted transaction"), plural(days, "day"))
+	shown := deleted[:min(trashListSize, len(deleted))]
+	for i := range shown {
+		content += h.commands.transactionLine(i+1, &shown[i])
+	}
+	if len(deleted) > len(shown) {
+		content += fmt.Sprintf("...and %d older\n", len(deleted)-len(shown))
+	}
+	content += "\nTap a number to restore that transaction."
+
+	keyboard := utils.BuildTrashKeyboard(shown)
+	return content, &keyboard
+}
+
+// handleUndo restores the transaction of a deletion notice
+func (h *EventHandler) handleUndo(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	transactionID := strings.TrimPrefix(callback.Data, "undo_")
+	chatID := callback.Message.Chat.ID
+
+	tx, err := h.db.RestoreTransaction(context.Background(), transactionID)
+	if err != nil {
+		log.Println("Failed to restore transaction:", err)
+		return
+	}
+
+	deleteMsg := tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID)
+	bot.Request(deleteMsg)
+	if tx != nil {
+		h.restoreTransactionMessage(bot, chatID, tx)
+	}
+}
+
+// handleRestore restores a transaction from the /trash list and redraws
+// the list
+func (h *EventHandler) handleRestore(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
+	transactionID := strings.TrimPrefix(callback.Data, "restore_")
+	chatID := callback.Message.Chat.ID
+
+	tx, err := h.db.RestoreTransaction(context.Background(), transactionID)
+	if err != nil {
+		log.Println("Failed to restore transaction:", err)
+		return
+	}
+	if tx != nil {
+		h.restoreTransactionMessage(bot, chatID, tx)
+	}
+
+	content, keyboard := h.trashMessage()
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, content)
+	editMsg.ReplyMarkup = keyboard
+	if _, err := bot.Send(editMsg); err != nil {
+		log.Println("Failed to update trash message:", err)
+	}
+}
+
+// restoreTransactionMessage brings back the bot message of a restored
+// transaction: a new message for a single transaction, or its line in the
+// summary of the message it came from
+func (h *EventHandler) restoreTransactionMessage(bot *tgbotapi.BotAPI, chatID int64, tx *models.Transaction) {
+	if tx.SourceMessageID == "" {
+		h.sendCategorySelection(bot, chatID, tx, true)
+		return
+	}
+
+	batch := h.batchTransactions(context.Background(), tx.SourceMessageID)
+	if len(batch) > 1 && tx.ButtonMessageID != "" {
+		summaryID, _ := strconv.Atoi(tx.ButtonMessageID)
+		h.updateBatchMessage(bot, chatID, summaryID, tx.SourceMessageID)
+		return
+	}
+	// The summary was deleted along with the last of its lines
+	h.sendBatchMessage(bot, chatID, batch)
+}
+
+// PurgeTrash permanently removes the transactions deleted longer ago than
+// the configured retention period
+func (h *CommandHandler) PurgeTrash() {
+	before := time.Now().Add(-h.config.TrashRetention).Unix()
+	purged, err := h.db.PurgeDeletedTransactions(context.Background(), before)
+	if err != nil {
+		log.Println("Failed to purge the trash:", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %s from the trash", plural(purged, "deleted transaction"))
+	}
+}
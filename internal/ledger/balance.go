@@ -0,0 +1,169 @@
+// Package ledger works out who owes whom from a set of transactions.
+package ledger
+
+import (
+	"sort"
+
+	"telegram-expense-bot/internal/models"
+)
+
+// Compute summarises transactions for the given members. Expenses are
+// credited to whoever paid and divided by their split, or equally between
+// their beneficiaries (all members by default). Refunds and income are
+// shared the same way in reverse, and settlements move money between two
+// members only; a settlement without a recipient is ignored. Anyone in a
+// transaction who is not listed counts as a member too.
+func Compute(transactions []models.Transaction, members []string) *models.Totals {
+	totals := &models.Totals{
+		UserTotals:     make(map[string]models.Money),
+		CategoryTotals: make(map[string]models.Money),
+	}
+	everyone := Participants(transactions, members)
+	contributed := make(map[string]models.Money)
+	owed := make(map[string]models.Money)
+	settled := make(map[string]models.Money)
+
+	for _, tx := range transactions {
+		amt := tx.HomeAmount()
+		payer := tx.PaidBy()
+
+		switch tx.Kind {
+		case models.KindSettlement:
+			// A payment without a recipient can't be credited to anyone,
+			// and counting half of it would leave the nets off balance
+			if tx.To == "" {
+				continue
+			}
+			// Paying someone back raises the payer's position and lowers the recipient's
+			settled[payer] += amt
+			settled[tx.To] -= amt
+			totals.TotalSettled += amt
+
+		case models.KindRefund:
+			// The payer got shared money back, so the shares shrink
+			contributed[payer] -= amt
+			for user, share := range Shares(amt, tx.Split, beneficiaries(&tx, everyone)) {
+				owed[user] -= share
+			}
+			totals.UserTotals[payer] -= amt
+			totals.TotalSpent -= amt
+			totals.TotalRefunded += amt
+			if tx.Category != "" {
+				totals.CategoryTotals[tx.Category] -= amt
+			}
+
+		case models.KindIncome:
+			// The payer holds money that belongs to everyone
+			contributed[payer] -= amt
+			for user, share := range Shares(amt, tx.Split, beneficiaries(&tx, everyone)) {
+				owed[user] -= share
+			}
+			totals.TotalIncome += amt
+
+		default:
+			contributed[payer] += amt
+			for user, share := range Shares(amt, tx.Split, beneficiaries(&tx, everyone)) {
+				owed[user] += share
+			}
+			totals.UserTotals[payer] += amt
+			totals.TotalSpent += amt
+			if tx.Category != "" {
+				totals.CategoryTotals[tx.Category] += amt
+			}
+		}
+	}
+
+	totals.Balances = make([]models.MemberBalance, 0, len(everyone))
+	for _, user := range everyone {
+		totals.Balances = append(totals.Balances, models.MemberBalance{
+			User:    user,
+			Paid:    contributed[user],
+			Share:   owed[user],
+			Settled: settled[user],
+			Net:     contributed[user] - owed[user] + settled[user],
+		})
+	}
+	SortBalances(totals.Balances)
+
+	totals.Outstanding = Outstanding(totals.Balances)
+	return totals
+}
+
+// Shares divides amount between users according to split. A nil or equal
+// split divides it evenly between everyone given. The shares always add up
+// to amount; see Money.Allocate for how odd cents are handed out.
+func Shares(amount models.Money, split *models.Split, everyone []string) map[string]models.Money {
+	if split.IsEqual() {
+		return amount.AllocateEqually(everyone)
+	}
+
+	// Percentages, shares and exact amounts are all proportional weights
+	shares := amount.Allocate(split.Weights)
+	if len(shares) == 0 {
+		return amount.AllocateEqually(everyone)
+	}
+	return shares
+}
+
+// beneficiaries returns who a transaction was for
+func beneficiaries(tx *models.Transaction, everyone []string) []string {
+	if len(tx.Beneficiaries) > 0 {
+		return tx.Beneficiaries
+	}
+	return everyone
+}
+
+// Participants returns the configured members plus everyone who appears in
+// the transactions, sorted and without duplicates
+func Participants(transactions []models.Transaction, members []string) []string {
+	seen := make(map[string]bool)
+	var everyone []string
+	add := func(user string) {
+		if user != "" && !seen[user] {
+			seen[user] = true
+			everyone = append(everyone, user)
+		}
+	}
+
+	for _, user := range members {
+		add(user)
+	}
+	for _, tx := range transactions {
+		add(tx.Author)
+		add(tx.Payer)
+		add(tx.To)
+		for _, user := range tx.Beneficiaries {
+			add(user)
+		}
+		if tx.Split != nil {
+			for user := range tx.Split.Weights {
+				add(user)
+			}
+		}
+	}
+
+	sort.Strings(everyone)
+	return everyone
+}
+
+// SortBalances orders balances by net position (highest first), breaking
+// ties by username so the result never depends on map iteration order
+func SortBalances(balances []models.MemberBalance) {
+	sort.SliceStable(balances, func(i, j int) bool {
+		if balances[i].Net != balances[j].Net {
+			return balances[i].Net > balances[j].Net
+		}
+		return balances[i].User < balances[j].User
+	})
+}
+
+// Outstanding returns the total amount owed by members with a negative net
+func Outstanding(balances []models.MemberBalance) models.Money {
+	var owed models.Money
+	for _, b := range balances {
+		if b.Net < 0 {
+			owed -= b.Net
+		}
+	}
+	return owed
+}
@@ -0,0 +1,220 @@
+package ledger
+
+import (
+	"reflect"
+	"testing"
+
+	"telegram-expense-bot/internal/models"
+)
+
+var members = []string{"alice", "bob", "carol"}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions []models.Transaction
+		members      []string
+		nets         map[string]models.Money
+		spent        models.Money
+		outstanding  models.Money
+	}{
+		{
+			name:        "nothing",
+			members:     members,
+			nets:        map[string]models.Money{"alice": 0, "bob": 0, "carol": 0},
+			outstanding: 0,
+		},
+		{
+			name: "one expense shared equally",
+			transactions: []models.Transaction{
+				{Author: "alice", Amount: 3000, Category: "Groceries"},
+			},
+			members:     members,
+			nets:        map[string]models.Money{"alice": 2000, "bob": -1000, "carol": -1000},
+			spent:       3000,
+			outstanding: 2000,
+		},
+		{
+			name: "odd cents",
+			transactions: []models.Transaction{
+				{Author: "alice", Amount: 1000},
+			},
+			members:     members,
+			nets:        map[string]models.Money{"alice": 666, "bob": -333, "carol": -333},
+			spent:       1000,
+			outstanding: 666,
+		},
+		{
+			name: "paid by someone else for some",
+			transactions: []models.Transaction{
+				{Author: "alice", Payer: "bob", Amount: 2000, Beneficiaries: []string{"alice", "carol"}},
+			},
+			members:     members,
+			nets:        map[string]models.Money{"alice": -1000, "bob": 2000, "carol": -1000},
+			spent:       2000,
+			outstanding: 2000,
+		},
+		{
+			name: "percentage split",
+			transactions: []models.Transaction{
+				{Author: "alice", Amount: 10000, Split: &models.Split{
+					Mode:    models.SplitPercent,
+					Weights: map[string]float64{"alice": 70, "bob": 30},
+				}},
+			},
+			members:     []string{"alice", "bob"},
+			nets:        map[string]models.Money{"alice": 3000, "bob": -3000},
+			spent:       10000,
+			outstanding: 3000,
+		},
+		{
+			name: "refund",
+			transactions: []models.Transaction{
+				{Author: "alice", Amount: 3000},
+				{Author: "alice", Kind: models.KindRefund, Amount: 600},
+			},
+			members:     members,
+			nets:        map[string]models.Money{"alice": 1600, "bob": -800, "carol": -800},
+			spent:       2400,
+			outstanding: 1600,
+		},
+		{
+			name: "income",
+			transactions: []models.Transaction{
+				{Author: "bob", Kind: models.KindIncome, Amount: 900},
+			},
+			members:     members,
+			nets:        map[string]models.Money{"alice": 300, "bob": -600, "carol": 300},
+			outstanding: 600,
+		},
+		{
+			name: "settlement",
+			transactions: []models.Transaction{
+				{Author: "alice", Amount: 3000},
+				{Author: "bob", Kind: models.KindSettlement, To: "alice", Amount: 1000},
+			},
+			members:     members,
+			nets:        map[string]models.Money{"alice": 1000, "bob": 0, "carol": -1000},
+			spent:       3000,
+			outstanding: 1000,
+		},
+		{
+			name: "settlement without a recipient",
+			transactions: []models.Transaction{
+				{Author: "alice", Amount: 3000},
+				{Author: "bob", Kind: models.KindSettlement, Amount: 1000},
+			},
+			members:     members,
+			nets:        map[string]models.Money{"alice": 2000, "bob": -1000, "carol": -1000},
+			spent:       3000,
+			outstanding: 2000,
+		},
+		{
+			name: "foreign currency",
+			transactions: []models.Transaction{
+				{Author: "alice", Amount: 2000, Currency: "EUR", Rate: 1.5},
+			},
+			members:     []string{"alice", "bob"},
+			nets:        map[string]models.Money{"alice": 1500, "bob": -1500},
+			spent:       3000,
+			outstanding: 1500,
+		},
+		{
+			name: "someone not in members",
+			transactions: []models.Transaction{
+				{Author: "dave", Amount: 4000},
+			},
+			members:     members,
+			nets:        map[string]models.Money{"alice": -1000, "bob": -1000, "carol": -1000, "dave": 3000},
+			spent:       4000,
+			outstanding: 3000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totals := Compute(tt.transactions, tt.members)
+
+			nets := make(map[string]models.Money)
+			var sum models.Money
+			for _, b := range totals.Balances {
+				nets[b.User] = b.Net
+				sum += b.Net
+				if b.Net != b.Paid-b.Share+b.Settled {
+					t.Errorf("%s: net %s isn't paid %s - share %s + settled %s", b.User, b.Net, b.Paid, b.Share, b.Settled)
+				}
+			}
+			if !reflect.DeepEqual(nets, tt.nets) {
+				t.Errorf("nets = %v, want %v", nets, tt.nets)
+			}
+			if sum != 0 {
+				t.Errorf("nets add up to %s, want 0", sum)
+			}
+			if totals.TotalSpent != tt.spent {
+				t.Errorf("TotalSpent = %s, want %s", totals.TotalSpent, tt.spent)
+			}
+			if totals.Outstanding != tt.outstanding {
+				t.Errorf("Outstanding = %s, want %s", totals.Outstanding, tt.outstanding)
+			}
+			for i := 1; i < len(totals.Balances); i++ {
+				if totals.Balances[i-1].Net < totals.Balances[i].Net {
+					t.Errorf("balances aren't sorted by net: %v", totals.Balances)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestShares(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   models.Money
+		split    *models.Split
+		everyone []string
+		want     map[string]models.Money
+	}{
+		{
+			name:     "no split",
+			amount:   1000,
+			everyone: members,
+			want:     map[string]models.Money{"alice": 334, "bob": 333, "carol": 333},
+		},
+		{
+			name:     "equal split",
+			amount:   900,
+			split:    &models.Split{Mode: models.SplitEqual},
+			everyone: members,
+			want:     map[string]models.Money{"alice": 300, "bob": 300, "carol": 300},
+		},
+		{
+			name:     "shares",
+			amount:   900,
+			split:    &models.Split{Mode: models.SplitShares, Weights: map[string]float64{"alice": 2, "bob": 1}},
+			everyone: members,
+			want:     map[string]models.Money{"alice": 600, "bob": 300},
+		},
+		{
+			name:     "exact",
+			amount:   2000,
+			split:    &models.Split{Mode: models.SplitExact, Weights: map[string]float64{"alice": 12.5, "carol": 7.5}},
+			everyone: members,
+			want:     map[string]models.Money{"alice": 1250, "carol": 750},
+		},
+		{
+			name:     "only zero weights fall back to equal",
+			amount:   1000,
+			split:    &models.Split{Mode: models.SplitShares, Weights: map[string]float64{"alice": 0}},
+			everyone: []string{"alice", "bob"},
+			want:     map[string]models.Money{"alice": 500, "bob": 500},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Shares(tt.amount, tt.split, tt.everyone); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Shares() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package ledger
+
+import (
+	"math/bits"
+	"sort"
+
+	"telegram-expense-bot/internal/models"
+)
+
+// exactLimit is the largest number of unsettled members for which Simplify
+// searches for the optimal plan; bigger groups use the greedy plan
+const exactLimit = 12
+
+// Simplify returns the smallest set of transfers that brings every balance
+// to zero. The fewest transfers come from splitting members into as many
+// groups that cancel out on their own as possible; each group of k members
+// then needs k-1 payments.
+func Simplify(balances []models.MemberBalance) []models.Transfer {
+	users, cents := unsettled(balances)
+	if len(users) == 0 {
+		return nil
+	}
+
+	var groups [][]int
+	if len(users) <= exactLimit {
+		groups = zeroSumGroups(cents)
+	} else {
+		all := make([]int, len(users))
+		for i := range all {
+			all[i] = i
+		}
+		groups = [][]int{all}
+	}
+
+	var transfers []models.Transfer
+	for _, group := range groups {
+		transfers = append(transfers, settleGroup(users, cents, group)...)
+	}
+
+	sort.SliceStable(transfers, func(i, j int) bool {
+		if transfers[i].Amount != transfers[j].Amount {
+			return transfers[i].Amount > transfers[j].Amount
+		}
+		if transfers[i].From != transfers[j].From {
+			return transfers[i].From < transfers[j].From
+		}
+		return transfers[i].To < transfers[j].To
+	})
+	return transfers
+}
+
+// unsettled returns the members with a non-zero net and their nets in cents.
+// Compute's nets always add up to zero; balances from older archives may be
+// a few cents off, and that remainder is absorbed by the member with the
+// largest position.
+func unsettled(balances []models.MemberBalance) ([]string, []int64) {
+	var users []string
+	var cents []int64
+	var sum int64
+	largest := -1
+
+	for _, b := range balances {
+		c := int64(b.Net)
+		if c == 0 {
+			continue
+		}
+		users = append(users, b.User)
+		cents = append(cents, c)
+		sum += c
+		if largest < 0 || abs(c) > abs(cents[largest]) {
+			largest = len(cents) - 1
+		}
+	}
+
+	if sum != 0 && largest >= 0 {
+		cents[largest] -= sum
+	}
+
+	// Drop anyone the correction brought to zero
+	var keptUsers []string
+	var keptCents []int64
+	for i, c := range cents {
+		if c != 0 {
+			keptUsers = append(keptUsers, users[i])
+			keptCents = append(keptCents, c)
+		}
+	}
+	return keptUsers, keptCents
+}
+
+// zeroSumGroups partitions members into the largest possible number of
+// groups whose nets sum to zero
+func zeroSumGroups(cents []int64) [][]int {
+	n := len(cents)
+	full := 1<<n - 1
+
+	sums := make([]int64, full+1)
+	for mask := 1; mask <= full; mask++ {
+		low := mask & -mask
+		i := bits.TrailingZeros(uint(low))
+		sums[mask] = sums[mask^low] + cents[i]
+	}
+
+	// best[mask] is the most zero-sum groups the members in mask can form
+	// when they are removed one at a time; choice remembers who to remove
+	best := make([]int, full+1)
+	choice := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		best[mask] = -1
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			if b := best[mask^(1<<i)]; b > best[mask] {
+				best[mask] = b
+				choice[mask] = i
+			}
+		}
+		if sums[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	// Walk the removal chain back from everyone; members removed between
+	// two consecutive zero-sum masks form one group
+	var groups [][]int
+	var current []int
+	for mask := full; mask != 0; {
+		i := choice[mask]
+		current = append(current, i)
+		mask ^= 1 << i
+		if sums[mask] == 0 {
+			groups = append(groups, current)
+			current = nil
+		}
+	}
+	return groups
+}
+
+// settleGroup settles a zero-sum group by repeatedly having the largest
+// debtor pay the largest creditor
+func settleGroup(users []string, cents []int64, group []int) []models.Transfer {
+	remaining := make(map[int]int64, len(group))
+	for _, i := range group {
+		remaining[i] = cents[i]
+	}
+
+	var transfers []models.Transfer
+	for {
+		debtor, creditor := -1, -1
+		for _, i := range group {
+			c := remaining[i]
+			if c < 0 && (debtor < 0 || c < remaining[debtor] || (c == remaining[debtor] && users[i] < users[debtor])) {
+				debtor = i
+			}
+			if c > 0 && (creditor < 0 || c > remaining[creditor] || (c == remaining[creditor] && users[i] < users[creditor])) {
+				creditor = i
+			}
+		}
+		if debtor < 0 || creditor < 0 {
+			return transfers
+		}
+
+		amount := -remaining[debtor]
+		if remaining[creditor] < amount {
+			amount = remaining[creditor]
+		}
+		remaining[debtor] += amount
+		remaining[creditor] -= amount
+
+		transfers = append(transfers, models.Transfer{
+			From:   users[debtor],
+			To:     users[creditor],
+			Amount: models.Money(amount),
+		})
+	}
+}
+
+func abs(c int64) int64 {
+	if c < 0 {
+		return -c
+	}
+	return c
+}
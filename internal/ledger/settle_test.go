@@ -0,0 +1,127 @@
+package ledger
+
+import (
+	"reflect"
+	"testing"
+
+	"telegram-expense-bot/internal/models"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances []models.MemberBalance
+		want     []models.Transfer
+	}{
+		{
+			name: "settled",
+			balances: []models.MemberBalance{
+				{User: "alice"}, {User: "bob"},
+			},
+		},
+		{
+			name: "one debtor",
+			balances: []models.MemberBalance{
+				{User: "alice", Net: 2000}, {User: "bob", Net: -1000}, {User: "carol", Net: -1000},
+			},
+			want: []models.Transfer{
+				{From: "bob", To: "alice", Amount: 1000},
+				{From: "carol", To: "alice", Amount: 1000},
+			},
+		},
+		{
+			name: "chain",
+			balances: []models.MemberBalance{
+				{User: "alice", Net: 3000}, {User: "bob", Net: -1000}, {User: "carol", Net: -2000},
+			},
+			want: []models.Transfer{
+				{From: "carol", To: "alice", Amount: 2000},
+				{From: "bob", To: "alice", Amount: 1000},
+			},
+		},
+		{
+			name: "pairs that cancel out",
+			balances: []models.MemberBalance{
+				{User: "alice", Net: 500}, {User: "bob", Net: 700},
+				{User: "carol", Net: -500}, {User: "dave", Net: -700},
+			},
+			want: []models.Transfer{
+				{From: "dave", To: "bob", Amount: 700},
+				{From: "carol", To: "alice", Amount: 500},
+			},
+		},
+		{
+			name: "a cent off is absorbed",
+			balances: []models.MemberBalance{
+				{User: "alice", Net: 1001}, {User: "bob", Net: -1000},
+			},
+			want: []models.Transfer{
+				{From: "bob", To: "alice", Amount: 1000},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Simplify(tt.balances); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Simplify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyFewestTransfers(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances []models.MemberBalance
+		want     int
+	}{
+		{
+			name: "two zero-sum groups",
+			balances: []models.MemberBalance{
+				{User: "alice", Net: 600}, {User: "bob", Net: 400},
+				{User: "carol", Net: -600}, {User: "dave", Net: -300}, {User: "erin", Net: -100},
+			},
+			want: 3,
+		},
+		{
+			name: "one group",
+			balances: []models.MemberBalance{
+				{User: "alice", Net: 600}, {User: "bob", Net: 400},
+				{User: "carol", Net: -500}, {User: "dave", Net: -300}, {User: "erin", Net: -200},
+			},
+			want: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Simplify(tt.balances)
+			if len(got) != tt.want {
+				t.Errorf("Simplify() made %d transfers, want %d: %v", len(got), tt.want, got)
+			}
+			checkSettles(t, tt.balances, got)
+		})
+	}
+}
+
+// checkSettles fails the test unless the transfers bring every balance to zero
+func checkSettles(t *testing.T, balances []models.MemberBalance, transfers []models.Transfer) {
+	t.Helper()
+	nets := make(map[string]models.Money)
+	for _, b := range balances {
+		nets[b.User] = b.Net
+	}
+	for _, tr := range transfers {
+		if tr.Amount <= 0 {
+			t.Errorf("transfer %v isn't positive", tr)
+		}
+		nets[tr.From] += tr.Amount
+		nets[tr.To] -= tr.Amount
+	}
+	for user, net := range nets {
+		if net != 0 {
+			t.Errorf("%s is left with %s after %v", user, net, transfers)
+		}
+	}
+}
@@ -1,20 +1,38 @@
 package models
 
+import "strconv"
+
 // MonthlyArchive represents archived monthly data
 type MonthlyArchive struct {
-	ID            string        `bson:"_id" json:"id"`                    // Format: "2025-01"
-	Year          int           `bson:"year" json:"year"`
-	Month         int           `bson:"month" json:"month"`
-	MonthName     string        `bson:"monthName" json:"monthName"`
-	TotalSpent    float64       `bson:"totalSpent" json:"totalSpent"`
-	TotalTransactions int       `bson:"totalTransactions" json:"totalTransactions"`
-	Balance       float64       `bson:"balance" json:"balance"`
-	UserTotals    map[string]float64 `bson:"userTotals" json:"userTotals"`
-	CategoryTotals map[string]float64 `bson:"categoryTotals" json:"categoryTotals"`
-	Transactions  []Transaction `bson:"transactions" json:"transactions"`
-	AvgTransaction float64      `bson:"avgTransaction" json:"avgTransaction"`
-	HighestTransaction float64  `bson:"highestTransaction" json:"highestTransaction"`
-	LowestTransaction float64   `bson:"lowestTransaction" json:"lowestTransaction"`
-	DaysWithSpending int        `bson:"daysWithSpending" json:"daysWithSpending"`
-	ArchivedAt    int64         `bson:"archivedAt" json:"archivedAt"`
-}
\ No newline at end of file
+	ID                 string           `bson:"_id" json:"id"` // Format: "2025-01"
+	Year               int              `bson:"year" json:"year"`
+	Month              int              `bson:"month" json:"month"`
+	MonthName          string           `bson:"monthName" json:"monthName"`
+	Label              string           `bson:"label,omitempty" json:"label,omitempty"` // Name of the archived period, e.g. "Mar 15 – Apr 14, 2025"
+	TotalSpent         Money            `bson:"totalSpent" json:"totalSpent"`
+	TotalTransactions  int              `bson:"totalTransactions" json:"totalTransactions"` // Expenses only, which the averages and extremes are over; Transactions holds every kind
+	TotalRefunded      Money            `bson:"totalRefunded,omitempty" json:"totalRefunded,omitempty"`
+	TotalIncome        Money            `bson:"totalIncome,omitempty" json:"totalIncome,omitempty"`
+	TotalSettled       Money            `bson:"totalSettled,omitempty" json:"totalSettled,omitempty"`
+	Balance            Money            `bson:"balance" json:"balance"` // Total owed by members with a negative net
+	Balances           []MemberBalance  `bson:"balances,omitempty" json:"balances,omitempty"`
+	UserTotals         map[string]Money `bson:"userTotals" json:"userTotals"`
+	CategoryTotals     map[string]Money `bson:"categoryTotals" json:"categoryTotals"`
+	Transactions       []Transaction    `bson:"transactions" json:"transactions"`
+	AvgTransaction     Money            `bson:"avgTransaction" json:"avgTransaction"`
+	HighestTransaction Money            `bson:"highestTransaction" json:"highestTransaction"`
+	LowestTransaction  Money            `bson:"lowestTransaction" json:"lowestTransaction"`
+	DaysWithSpending   int              `bson:"daysWithSpending" json:"daysWithSpending"`
+	PeriodStart        int64            `bson:"periodStart,omitempty" json:"periodStart,omitempty"` // Unix time the archived period starts
+	PeriodEnd          int64            `bson:"periodEnd,omitempty" json:"periodEnd,omitempty"`     // Unix time the next period starts
+	ArchivedAt         int64            `bson:"archivedAt" json:"archivedAt"`
+}
+
+// PeriodLabel names the archived period. Archives made before periods had
+// labels were always calendar months.
+func (a *MonthlyArchive) PeriodLabel() string {
+	if a.Label != "" {
+		return a.Label
+	}
+	return a.MonthName + " " + strconv.Itoa(a.Year)
+}
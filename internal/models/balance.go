@@ -0,0 +1,29 @@
+package models
+
+// MemberBalance is one member's position against their equal share
+type MemberBalance struct {
+	User    string `bson:"user" json:"user"`
+	Paid    Money  `bson:"paid" json:"paid"`       // Shared expenses paid, less refunds and income received
+	Share   Money  `bson:"share" json:"share"`     // The member's share of all expenses
+	Settled Money  `bson:"settled" json:"settled"` // Settlements sent minus settlements received
+	Net     Money  `bson:"net" json:"net"`         // Paid - Share + Settled: positive is owed, negative owes
+}
+
+// Totals is the summary of a set of transactions shared by every report
+type Totals struct {
+	Balances       []MemberBalance  // Sorted by Net (highest first), then by user
+	Outstanding    Money            // Total owed by members with a negative net
+	UserTotals     map[string]Money // Amount spent per user, net of refunds
+	CategoryTotals map[string]Money
+	TotalSpent     Money // Expenses less refunds
+	TotalRefunded  Money
+	TotalIncome    Money
+	TotalSettled   Money
+}
+
+// Transfer is a payment from one member to another that settles debts
+type Transfer struct {
+	From   string
+	To     string
+	Amount Money
+}
@@ -0,0 +1,10 @@
+package models
+
+// Lease is a lock kept in the database, so that only one bot process runs
+// a job at a time. A holder that dies without releasing it loses it when
+// it expires.
+type Lease struct {
+	ID        string `bson:"_id" json:"id"`      // Name of the job, e.g. "reset"
+	Owner     string `bson:"owner" json:"owner"` // Random ID of the run holding it
+	ExpiresAt int64  `bson:"expiresAt" json:"expiresAt"`
+}
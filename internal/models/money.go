@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (cents). All sums, shares and balances
+// are kept in Money so they add up exactly; float64 is only used for rates,
+// weights and percentages.
+type Money int64
+
+// FromFloat converts a decimal amount to Money, rounding half away from zero
+func FromFloat(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// ParseMoney parses a decimal amount such as "25", "25.5" or "25.50".
+// Anything past the second decimal is rounded half away from zero.
+func ParseMoney(text string) (Money, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return 0, fmt.Errorf("invalid amount format")
+	}
+	return FromFloat(amount), nil
+}
+
+// Float returns the amount in major units, for percentages and display
+func (m Money) Float() float64 {
+	return float64(m) / 100
+}
+
+// Abs returns the absolute amount
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// Mul multiplies the amount by a rate, rounding half away from zero
+func (m Money) Mul(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// Div divides the amount by n, rounding half away from zero
+func (m Money) Div(n int) Money {
+	if n == 0 {
+		return 0
+	}
+	return Money(math.Round(float64(m) / float64(n)))
+}
+
+// String formats the amount with two decimals, e.g. "-12.50"
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+	}
+	abs := m.Abs()
+	return fmt.Sprintf("%s%d.%02d", sign, abs/100, abs%100)
+}
+
+// Allocate divides the amount between users in proportion to their weights
+// so that the parts add up to the amount exactly. Each part is first rounded
+// toward zero to a whole cent; the cents left over then go one at a time to
+// the users with the largest remainders, ties going to the user who sorts
+// first. Users with a zero or negative weight get nothing.
+func (m Money) Allocate(weights map[string]float64) map[string]Money {
+	users := make([]string, 0, len(weights))
+	total := 0.0
+	for user, weight := range weights {
+		if weight > 0 {
+			users = append(users, user)
+			total += weight
+		}
+	}
+	parts := make(map[string]Money, len(users))
+	if len(users) == 0 || total <= 0 {
+		return parts
+	}
+	sort.Strings(users)
+
+	sign := Money(1)
+	if m < 0 {
+		sign = -1
+	}
+	cents := m.Abs()
+
+	remainders := make(map[string]float64, len(users))
+	left := cents
+	for _, user := range users {
+		exact := float64(cents) * weights[user] / total
+		part := Money(math.Floor(exact))
+		parts[user] = part
+		remainders[user] = exact - float64(part)
+		left -= part
+	}
+
+	order := append([]string(nil), users...)
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+	for i := 0; left > 0; i = (i + 1) % len(order) {
+		parts[order[i]]++
+		left--
+	}
+
+	for user := range parts {
+		parts[user] *= sign
+	}
+	return parts
+}
+
+// AllocateEqually divides the amount into equal parts for users, following
+// the same rounding rule as Allocate: the extra cents of an uneven split go
+// to the users who sort first
+func (m Money) AllocateEqually(users []string) map[string]Money {
+	weights := make(map[string]float64, len(users))
+	for _, user := range users {
+		weights[user] = 1
+	}
+	return m.Allocate(weights)
+}
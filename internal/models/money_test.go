@@ -0,0 +1,146 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  Money
+		weights map[string]float64
+		want    map[string]Money
+	}{
+		{
+			name:    "even",
+			amount:  1000,
+			weights: map[string]float64{"alice": 1, "bob": 1},
+			want:    map[string]Money{"alice": 500, "bob": 500},
+		},
+		{
+			name:    "odd cent goes to the user who sorts first",
+			amount:  1001,
+			weights: map[string]float64{"bob": 1, "alice": 1},
+			want:    map[string]Money{"alice": 501, "bob": 500},
+		},
+		{
+			name:    "thirds",
+			amount:  10000,
+			weights: map[string]float64{"alice": 1, "bob": 1, "carol": 1},
+			want:    map[string]Money{"alice": 3334, "bob": 3333, "carol": 3333},
+		},
+		{
+			name:    "largest remainder first",
+			amount:  100,
+			weights: map[string]float64{"alice": 1, "bob": 2},
+			want:    map[string]Money{"alice": 33, "bob": 67},
+		},
+		{
+			name:    "percentages",
+			amount:  4999,
+			weights: map[string]float64{"alice": 70, "bob": 30},
+			want:    map[string]Money{"alice": 3499, "bob": 1500},
+		},
+		{
+			name:    "negative amount",
+			amount:  -1001,
+			weights: map[string]float64{"alice": 1, "bob": 1},
+			want:    map[string]Money{"alice": -501, "bob": -500},
+		},
+		{
+			name:    "zero weight gets nothing",
+			amount:  1000,
+			weights: map[string]float64{"alice": 1, "bob": 0},
+			want:    map[string]Money{"alice": 1000},
+		},
+		{
+			name:    "no positive weights",
+			amount:  1000,
+			weights: map[string]float64{"alice": 0},
+			want:    map[string]Money{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.amount.Allocate(tt.weights)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Allocate(%v) = %v, want %v", tt.weights, got, tt.want)
+			}
+			var sum Money
+			for _, part := range got {
+				sum += part
+			}
+			if len(got) > 0 && sum != tt.amount {
+				t.Errorf("parts add up to %s, want %s", sum, tt.amount)
+			}
+		})
+	}
+}
+
+func TestAllocateEqually(t *testing.T) {
+	tests := []struct {
+		amount Money
+		users  []string
+		want   map[string]Money
+	}{
+		{1000, []string{"alice", "bob"}, map[string]Money{"alice": 500, "bob": 500}},
+		{1002, []string{"carol", "bob", "alice"}, map[string]Money{"alice": 334, "bob": 334, "carol": 334}},
+		{1003, []string{"carol", "bob", "alice", "dave"}, map[string]Money{"alice": 251, "bob": 251, "carol": 251, "dave": 250}},
+		{1000, nil, map[string]Money{}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.AllocateEqually(tt.users); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s.AllocateEqually(%v) = %v, want %v", tt.amount, tt.users, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		amount Money
+		want   string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{2550, "25.50"},
+		{-1250, "-12.50"},
+		{-5, "-0.05"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.amount), got, tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Money
+		wantErr bool
+	}{
+		{"25", 2500, false},
+		{"25.5", 2550, false},
+		{" 25.50 ", 2550, false},
+		{"0.005", 1, false},
+		{"-0.005", -1, false},
+		{"abc", 0, true},
+		{"Inf", 0, true},
+		{"NaN", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMoney(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Period is a stretch of time transactions are reported and archived by,
+// from Start up to but not including End
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MonthPeriod returns the calendar month t falls in, in t's location
+func MonthPeriod(t time.Time) Period {
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return Period{Start: start, End: start.AddDate(0, 1, 0)}
+}
+
+// Contains checks if a Unix time falls within the period
+func (p Period) Contains(unix int64) bool {
+	return unix >= p.Start.Unix() && unix < p.End.Unix()
+}
+
+// Days returns the number of calendar days in the period
+func (p Period) Days() int {
+	return int(p.End.Sub(p.Start).Hours()/24 + 0.5)
+}
+
+// IsCalendarMonth checks if the period runs from the 1st of a month to the
+// 1st of the next
+func (p Period) IsCalendarMonth() bool {
+	return p.Start.Day() == 1 && p.End.Equal(p.Start.AddDate(0, 1, 0))
+}
+
+// ID returns the archive ID of the period: "2025-03" for a calendar month,
+// otherwise its first day, e.g. "2025-03-15"
+func (p Period) ID() string {
+	if p.IsCalendarMonth() {
+		return p.Start.Format("2006-01")
+	}
+	return p.Start.Format("2006-01-02")
+}
+
+// Label names the period in reports: "March 2025" for a calendar month,
+// otherwise its first and last day, e.g. "Mar 15 – Apr 14, 2025"
+func (p Period) Label() string {
+	if p.IsCalendarMonth() {
+		return p.Start.Format("January 2006")
+	}
+	last := p.End.AddDate(0, 0, -1)
+	if last.Year() != p.Start.Year() {
+		return fmt.Sprintf("%s – %s", p.Start.Format("Jan 2, 2006"), last.Format("Jan 2, 2006"))
+	}
+	return fmt.Sprintf("%s – %s", p.Start.Format("Jan 2"), last.Format("Jan 2, 2006"))
+}
+
+// Cycle lengths selectable through RESET_CYCLE
+const (
+	CycleMonthly  = "monthly"
+	CycleWeekly   = "weekly"
+	CycleBiweekly = "biweekly"
+)
+
+// Cycle cuts time into periods. Monthly periods start on the anchor's day
+// of the month, e.g. payday on the 15th, or on the last day of shorter
+// months. Weekly and biweekly periods count whole weeks from the anchor.
+// The zero Cycle is the calendar month.
+type Cycle struct {
+	Length string
+	Anchor time.Time // Midnight of a day a period starts on, in the cycle's location
+}
+
+// PeriodAt returns the period t falls in
+func (c Cycle) PeriodAt(t time.Time) Period {
+	if c.Anchor.IsZero() {
+		if c.Length == CycleMonthly || c.Length == "" {
+			return MonthPeriod(t)
+		}
+		c.Anchor = time.Date(2024, time.January, 1, 0, 0, 0, 0, t.Location()) // A Monday
+	}
+	t = t.In(c.Anchor.Location())
+
+	switch c.Length {
+	case CycleWeekly, CycleBiweekly:
+		days := 7
+		if c.Length == CycleBiweekly {
+			days = 14
+		}
+		// Count calendar days, which daylight saving time doesn't stretch
+		anchor := time.Date(c.Anchor.Year(), c.Anchor.Month(), c.Anchor.Day(), 0, 0, 0, 0, time.UTC)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		elapsed := int(day.Sub(anchor).Hours() / 24)
+		periods := elapsed / days
+		if elapsed < 0 && elapsed%days != 0 {
+			periods--
+		}
+		start := c.Anchor.AddDate(0, 0, periods*days)
+		return Period{Start: start, End: start.AddDate(0, 0, days)}
+	}
+
+	start := c.monthStart(t.Year(), t.Month())
+	if t.Before(start) {
+		start = c.monthStart(t.Year(), t.Month()-1)
+	}
+	return Period{Start: start, End: c.monthStart(start.Year(), start.Month()+1)}
+}
+
+// monthStart returns the day a monthly period starts on in the given month
+func (c Cycle) monthStart(year int, month time.Month) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, c.Anchor.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(c.Anchor.Day(), lastDay)-1)
+}
+
+// Previous returns the period right before p
+func (c Cycle) Previous(p Period) Period {
+	return c.PeriodAt(p.Start.Add(-time.Second))
+}
+
+// PeriodByID returns the period of the cycle with the given ID (see
+// Period.ID), e.g. "2025-03" for a calendar month or "2025-03-15" for a
+// period starting that day. It reports false for any other ID, such as a
+// day in the middle of a period.
+func (c Cycle) PeriodByID(id string) (Period, bool) {
+	layout := "2006-01-02"
+	if len(id) == len("2006-01") {
+		layout = "2006-01"
+	}
+	start, err := time.ParseInLocation(layout, id, c.Anchor.Location())
+	if err != nil {
+		return Period{}, false
+	}
+	period := c.PeriodAt(start)
+	return period, period.ID() == id
+}
+
+// Adjective names the cycle in reports, e.g. "monthly"
+func (c Cycle) Adjective() string {
+	if c.Length == "" {
+		return CycleMonthly
+	}
+	return c.Length
+}
+
+// Noun names one period of the cycle in reports, e.g. "month"
+func (c Cycle) Noun() string {
+	switch c.Length {
+	case CycleWeekly:
+		return "week"
+	case CycleBiweekly:
+		return "fortnight"
+	}
+	return "month"
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCyclePeriodAt(t *testing.T) {
+	tests := []struct {
+		name      string
+		cycle     Cycle
+		at        time.Time
+		start     time.Time
+		end       time.Time
+		id, label string
+	}{
+		{
+			name:  "calendar month",
+			cycle: Cycle{},
+			at:    time.Date(2025, time.March, 14, 9, 15, 0, 0, time.UTC),
+			start: date(2025, time.March, 1),
+			end:   date(2025, time.April, 1),
+			id:    "2025-03",
+			label: "March 2025",
+		},
+		{
+			name:  "monthly from the 15th",
+			cycle: Cycle{Length: CycleMonthly, Anchor: date(2025, time.January, 15)},
+			at:    date(2025, time.March, 14),
+			start: date(2025, time.February, 15),
+			end:   date(2025, time.March, 15),
+			id:    "2025-02-15",
+			label: "Feb 15 – Mar 14, 2025",
+		},
+		{
+			name:  "monthly from the 15th, on the day",
+			cycle: Cycle{Length: CycleMonthly, Anchor: date(2025, time.January, 15)},
+			at:    date(2025, time.March, 15),
+			start: date(2025, time.March, 15),
+			end:   date(2025, time.April, 15),
+			id:    "2025-03-15",
+			label: "Mar 15 – Apr 14, 2025",
+		},
+		{
+			name:  "monthly from the 31st in a short month",
+			cycle: Cycle{Length: CycleMonthly, Anchor: date(2025, time.January, 31)},
+			at:    date(2025, time.March, 1),
+			start: date(2025, time.February, 28),
+			end:   date(2025, time.March, 31),
+			id:    "2025-02-28",
+			label: "Feb 28 – Mar 30, 2025",
+		},
+		{
+			name:  "monthly across the new year",
+			cycle: Cycle{Length: CycleMonthly, Anchor: date(2025, time.January, 15)},
+			at:    date(2026, time.January, 2),
+			start: date(2025, time.December, 15),
+			end:   date(2026, time.January, 15),
+			id:    "2025-12-15",
+			label: "Dec 15, 2025 – Jan 14, 2026",
+		},
+		{
+			name:  "weekly from a Monday",
+			cycle: Cycle{Length: CycleWeekly, Anchor: date(2025, time.March, 3)},
+			at:    date(2025, time.March, 16),
+			start: date(2025, time.March, 10),
+			end:   date(2025, time.March, 17),
+			id:    "2025-03-10",
+			label: "Mar 10 – Mar 16, 2025",
+		},
+		{
+			name:  "weekly before the anchor",
+			cycle: Cycle{Length: CycleWeekly, Anchor: date(2025, time.March, 3)},
+			at:    date(2025, time.March, 1),
+			start: date(2025, time.February, 24),
+			end:   date(2025, time.March, 3),
+			id:    "2025-02-24",
+			label: "Feb 24 – Mar 2, 2025",
+		},
+		{
+			name:  "weekly without an anchor starts on Monday",
+			cycle: Cycle{Length: CycleWeekly},
+			at:    date(2025, time.March, 14),
+			start: date(2025, time.March, 10),
+			end:   date(2025, time.March, 17),
+			id:    "2025-03-10",
+			label: "Mar 10 – Mar 16, 2025",
+		},
+		{
+			name:  "biweekly",
+			cycle: Cycle{Length: CycleBiweekly, Anchor: date(2025, time.March, 3)},
+			at:    date(2025, time.March, 30),
+			start: date(2025, time.March, 17),
+			end:   date(2025, time.March, 31),
+			id:    "2025-03-17",
+			label: "Mar 17 – Mar 30, 2025",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.cycle.PeriodAt(tt.at)
+			if !p.Start.Equal(tt.start) || !p.End.Equal(tt.end) {
+				t.Fatalf("PeriodAt(%s) = %s to %s, want %s to %s", tt.at, p.Start, p.End, tt.start, tt.end)
+			}
+			if got := p.ID(); got != tt.id {
+				t.Errorf("ID() = %q, want %q", got, tt.id)
+			}
+			if got := p.Label(); got != tt.label {
+				t.Errorf("Label() = %q, want %q", got, tt.label)
+			}
+			if !p.Contains(tt.at.Unix()) || p.Contains(p.End.Unix()) {
+				t.Errorf("period %s doesn't contain %s or contains its end", p.ID(), tt.at)
+			}
+		})
+	}
+}
+
+func TestCycleWeeklyAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data not available:", err)
+	}
+	cycle := Cycle{Length: CycleWeekly, Anchor: time.Date(2025, time.March, 24, 0, 0, 0, 0, loc)}
+
+	// Clocks go forward on March 30, 2025; the next period must still
+	// start at midnight
+	p := cycle.PeriodAt(time.Date(2025, time.April, 1, 12, 0, 0, 0, loc))
+	want := time.Date(2025, time.March, 31, 0, 0, 0, 0, loc)
+	if !p.Start.Equal(want) {
+		t.Errorf("period starts %s, want %s", p.Start, want)
+	}
+}
+
+func TestCyclePrevious(t *testing.T) {
+	tests := []struct {
+		name  string
+		cycle Cycle
+		at    time.Time
+		want  string
+	}{
+		{"calendar month", Cycle{}, date(2025, time.January, 10), "2024-12"},
+		{"monthly from the 31st", Cycle{Length: CycleMonthly, Anchor: date(2025, time.January, 31)}, date(2025, time.March, 31), "2025-02-28"},
+		{"weekly", Cycle{Length: CycleWeekly, Anchor: date(2025, time.March, 3)}, date(2025, time.March, 12), "2025-03-03"},
+		{"biweekly", Cycle{Length: CycleBiweekly, Anchor: date(2025, time.March, 3)}, date(2025, time.March, 20), "2025-03-03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cycle.Previous(tt.cycle.PeriodAt(tt.at)).ID(); got != tt.want {
+				t.Errorf("Previous period = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCyclePeriodByID(t *testing.T) {
+	monthly15 := Cycle{Length: CycleMonthly, Anchor: date(2025, time.January, 15)}
+	weekly := Cycle{Length: CycleWeekly, Anchor: date(2025, time.March, 3)}
+
+	tests := []struct {
+		name  string
+		cycle Cycle
+		id    string
+		start time.Time
+		ok    bool
+	}{
+		{"calendar month", Cycle{}, "2025-03", date(2025, time.March, 1), true},
+		{"day of a calendar month", Cycle{}, "2025-03-01", time.Time{}, false},
+		{"anchored month", monthly15, "2025-03-15", date(2025, time.March, 15), true},
+		{"middle of an anchored month", monthly15, "2025-03-20", time.Time{}, false},
+		{"month ID for an anchored cycle", monthly15, "2025-03", time.Time{}, false},
+		{"week", weekly, "2025-03-10", date(2025, time.March, 10), true},
+		{"middle of a week", weekly, "2025-03-12", time.Time{}, false},
+		{"not a date", Cycle{}, "march", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := tt.cycle.PeriodByID(tt.id)
+			if ok != tt.ok {
+				t.Fatalf("PeriodByID(%q) ok = %v, want %v", tt.id, ok, tt.ok)
+			}
+			if ok && !p.Start.Equal(tt.start) {
+				t.Errorf("PeriodByID(%q) starts %s, want %s", tt.id, p.Start, tt.start)
+			}
+		})
+	}
+}
+
+func TestCycleNames(t *testing.T) {
+	tests := []struct {
+		cycle           Cycle
+		noun, adjective string
+	}{
+		{Cycle{}, "month", "monthly"},
+		{Cycle{Length: CycleMonthly}, "month", "monthly"},
+		{Cycle{Length: CycleWeekly}, "week", "weekly"},
+		{Cycle{Length: CycleBiweekly}, "fortnight", "biweekly"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cycle.Noun(); got != tt.noun {
+			t.Errorf("Cycle{%q}.Noun() = %q, want %q", tt.cycle.Length, got, tt.noun)
+		}
+		if got := tt.cycle.Adjective(); got != tt.adjective {
+			t.Errorf("Cycle{%q}.Adjective() = %q, want %q", tt.cycle.Length, got, tt.adjective)
+		}
+	}
+}
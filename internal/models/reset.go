@@ -0,0 +1,33 @@
+package models
+
+// resetStateID is the ID of the one ResetState document
+const resetStateID = "periodic"
+
+// NewResetState records period as the last one the periodic reset completed
+func NewResetState(period Period, completedAt int64) *ResetState {
+	return &ResetState{
+		ID:          resetStateID,
+		PeriodStart: period.Start.Unix(),
+		PeriodEnd:   period.End.Unix(),
+		CompletedAt: completedAt,
+	}
+}
+
+// ResetSnapshot is a copy of every transaction, the trash included, taken
+// right before /reset wipes them
+type ResetSnapshot struct {
+	ID           string        `bson:"_id" json:"id"` // Format: "20250314-091500-1a2b3c4d"
+	By           string        `bson:"by" json:"by"`  // Admin who confirmed the reset
+	Transactions []Transaction `bson:"transactions" json:"transactions"`
+	CreatedAt    int64         `bson:"createdAt" json:"createdAt"`
+	RestoredAt   int64         `bson:"restoredAt,omitempty" json:"restoredAt,omitempty"` // Unix time /restore brought it back, so it can't again
+}
+
+// ResetState records the last period the periodic reset completed, so the
+// periods missed while the bot was down can be caught up on
+type ResetState struct {
+	ID          string `bson:"_id" json:"id"`                  // Always "periodic"
+	PeriodStart int64  `bson:"periodStart" json:"periodStart"` // Unix time the completed period started
+	PeriodEnd   int64  `bson:"periodEnd" json:"periodEnd"`     // Unix time the next period starts
+	CompletedAt int64  `bson:"completedAt" json:"completedAt"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "strings"
+
+// SearchFilter selects transactions for /search. Zero fields match everything.
+type SearchFilter struct {
+	Words     []string // Lowercase words that must all appear in the note
+	MinAmount Money    // Smallest home amount, inclusive
+	MaxAmount Money    // Largest home amount, inclusive; 0 for no limit
+	Category  string
+	User      string // Author or payer
+	From      int64  // Earliest CreatedAt, inclusive; 0 for no limit
+	Until     int64  // Latest CreatedAt, exclusive; 0 for no limit
+}
+
+// Matches reports whether a transaction passes every filter
+func (f *SearchFilter) Matches(tx *Transaction) bool {
+	note := strings.ToLower(tx.Note)
+	for _, word := range f.Words {
+		if !strings.Contains(note, word) {
+			return false
+		}
+	}
+
+	amount := tx.HomeAmount()
+	if amount < f.MinAmount || (f.MaxAmount > 0 && amount > f.MaxAmount) {
+		return false
+	}
+	if f.Category != "" && tx.Category != f.Category {
+		return false
+	}
+	if f.User != "" && tx.Author != f.User && tx.PaidBy() != f.User {
+		return false
+	}
+	if (f.From != 0 && tx.CreatedAt < f.From) || (f.Until != 0 && tx.CreatedAt >= f.Until) {
+		return false
+	}
+	return true
+}
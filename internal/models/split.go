@@ -0,0 +1,22 @@
+package models
+
+// Split modes
+const (
+	SplitEqual   = "equal"   // Everyone pays the same
+	SplitPercent = "percent" // Weights are percentages adding up to 100
+	SplitShares  = "shares"  // Weights are relative shares, e.g. 2 and 1
+	SplitExact   = "exact"   // Weights are amounts adding up to the total
+)
+
+// Split says how a transaction is divided between members. Members missing
+// from Weights pay nothing; a nil Split or an equal split covers everyone.
+type Split struct {
+	Mode         string             `bson:"mode" json:"mode"`
+	Weights      map[string]float64 `bson:"weights,omitempty" json:"weights,omitempty"`
+	FromCategory bool               `bson:"fromCategory,omitempty" json:"fromCategory,omitempty"` // Set from the category's default rule
+}
+
+// IsEqual reports whether the split divides the amount equally between everyone
+func (s *Split) IsEqual() bool {
+	return s == nil || s.Mode == SplitEqual || len(s.Weights) == 0
+}
@@ -1,12 +1,86 @@
 package models
 
+// Transaction kinds. An empty kind is an expense, which keeps documents
+// written before kinds existed valid.
+const (
+	KindExpense    = "expense"    // Paid by the author, shared by everyone
+	KindSettlement = "settlement" // Paid by the author directly to To
+	KindRefund     = "refund"     // Money returned to the author for a shared expense
+	KindIncome     = "income"     // Shared money received by the author
+)
+
 // Transaction represents one record in MongoDB.
 type Transaction struct {
-	ID                  string   `bson:"_id" json:"id"`
-	Amount              float64  `bson:"amount" json:"amount"`
-	Author              string   `bson:"author" json:"author"`
-	Category            string   `bson:"category,omitempty" json:"category,omitempty"`
-	ButtonMessageID     string   `bson:"buttonMessageId,omitempty" json:"buttonMessageId,omitempty"`
-	ConfirmationMessageID string `bson:"confirmationMessageId,omitempty" json:"confirmationMessageId,omitempty"`
-	CreatedAt           int64    `bson:"createdAt" json:"createdAt"`
-}
\ No newline at end of file
+	ID                    string   `bson:"_id" json:"id"`
+	Kind                  string   `bson:"kind,omitempty" json:"kind,omitempty"`
+	Amount                Money    `bson:"amount" json:"amount"`                                   // In Currency
+	Expression            string   `bson:"expression,omitempty" json:"expression,omitempty"`       // What the amount was typed as, e.g. "12.50+8.99+3"
+	Currency              string   `bson:"currency,omitempty" json:"currency,omitempty"`           // Original currency, when not the home currency
+	Rate                  float64  `bson:"rate,omitempty" json:"rate,omitempty"`                   // Home currency per unit of Currency at entry time
+	Author                string   `bson:"author" json:"author"`                                   // Who logged the transaction
+	Payer                 string   `bson:"payer,omitempty" json:"payer,omitempty"`                 // Who paid, when not the author
+	Beneficiaries         []string `bson:"beneficiaries,omitempty" json:"beneficiaries,omitempty"` // Who it was for, when not everyone
+	To                    string   `bson:"to,omitempty" json:"to,omitempty"`                       // Recipient of a settlement
+	Category              string   `bson:"category,omitempty" json:"category,omitempty"`
+	Note                  string   `bson:"note,omitempty" json:"note,omitempty"` // Free text from the message, e.g. the merchant
+	Split                 *Split   `bson:"split,omitempty" json:"split,omitempty"`
+	ButtonMessageID       string   `bson:"buttonMessageId,omitempty" json:"buttonMessageId,omitempty"`
+	ConfirmationMessageID string   `bson:"confirmationMessageId,omitempty" json:"confirmationMessageId,omitempty"`
+	SourceMessageID       string   `bson:"sourceMessageId,omitempty" json:"sourceMessageId,omitempty"` // Message with one transaction per line this came from
+	Line                  int      `bson:"line,omitempty" json:"line,omitempty"`                       // Line of the source message, from 1
+	CreatedAt             int64    `bson:"createdAt" json:"createdAt"`                                 // Set on insert unless the message gave a date
+	RecordedAt            int64    `bson:"recordedAt,omitempty" json:"recordedAt,omitempty"`           // When its message was sent, whatever date it is for
+	DeletedAt             int64    `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`             // When it was moved to the trash
+}
+
+// Recorded returns when the transaction was logged. Transactions saved
+// before that was kept only have the date they are for.
+func (tx *Transaction) Recorded() int64 {
+	if tx.RecordedAt != 0 {
+		return tx.RecordedAt
+	}
+	return tx.CreatedAt
+}
+
+// HomeAmount returns the absolute amount converted to the home currency
+func (tx *Transaction) HomeAmount() Money {
+	amount := tx.Amount.Abs()
+	if tx.Currency != "" && tx.Rate > 0 {
+		return amount.Mul(tx.Rate)
+	}
+	return amount
+}
+
+// PaidBy returns who paid: the payer if set, otherwise the author
+func (tx *Transaction) PaidBy() string {
+	if tx.Payer != "" {
+		return tx.Payer
+	}
+	return tx.Author
+}
+
+// IsExpense reports whether the transaction is a shared expense
+func (tx *Transaction) IsExpense() bool {
+	return tx.Kind == "" || tx.Kind == KindExpense
+}
+
+// IsSettlement reports whether the transaction is a payment between members
+func (tx *Transaction) IsSettlement() bool {
+	return tx.Kind == KindSettlement
+}
+
+// HasCategory reports whether the transaction is filed under a spending category
+func (tx *Transaction) HasCategory() bool {
+	return tx.IsExpense() || tx.Kind == KindRefund
+}
+
+// Expenses returns only the shared expenses from a list of transactions
+func Expenses(transactions []Transaction) []Transaction {
+	var expenses []Transaction
+	for _, tx := range transactions {
+		if tx.IsExpense() {
+			expenses = append(expenses, tx)
+		}
+	}
+	return expenses
+}
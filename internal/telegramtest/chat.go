@@ -0,0 +1,140 @@
+package telegramtest
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Chat replays scripted user activity in one chat and hands the resulting
+// updates to a handler, the same way main.go routes them from the update channel
+type Chat struct {
+	ID     int64
+	Server *Server
+	Handle func(update tgbotapi.Update)
+
+	nextUpdateID int64
+	users        map[string]*tgbotapi.User
+}
+
+// NewChat creates a chat driver bound to a server
+func NewChat(server *Server, chatID int64, handle func(update tgbotapi.Update)) *Chat {
+	return &Chat{
+		ID:     chatID,
+		Server: server,
+		Handle: handle,
+		users:  make(map[string]*tgbotapi.User),
+	}
+}
+
+// Send posts a new text message from a user. Texts starting with "/" are
+// marked as bot commands.
+func (c *Chat) Send(username, text string) *tgbotapi.Message {
+	msg := c.newMessage(username, text)
+	c.dispatch(tgbotapi.Update{Message: msg})
+	return msg
+}
+
+// Reply posts a text message replying to another message
+func (c *Chat) Reply(username string, to int, text string) *tgbotapi.Message {
+	msg := c.newMessage(username, text)
+	msg.ReplyToMessage = &tgbotapi.Message{MessageID: to, Chat: msg.Chat}
+	if original, ok := c.Server.Message(to); ok {
+		msg.ReplyToMessage.Text = original.Text
+		msg.ReplyToMessage.From = &tgbotapi.User{ID: 1, IsBot: true, UserName: BotUserName}
+	}
+	c.dispatch(tgbotapi.Update{Message: msg})
+	return msg
+}
+
+// Edit changes the text of a message previously sent with Send
+func (c *Chat) Edit(msg *tgbotapi.Message, text string) *tgbotapi.Message {
+	edited := *msg
+	edited.Text = text
+	edited.Entities = commandEntities(text)
+	edited.EditDate = int(time.Now().Unix())
+	c.dispatch(tgbotapi.Update{EditedMessage: &edited})
+	return &edited
+}
+
+// Delete removes a message previously sent with Send. Telegram doesn't
+// notify bots of deletions, so no update is dispatched.
+func (c *Chat) Delete(msg *tgbotapi.Message) {
+	c.Server.DeleteUserMessage(msg.MessageID)
+}
+
+// Press taps a button by its callback data on a bot message
+func (c *Chat) Press(username string, messageID int, data string) string {
+	msg, ok := c.Server.Message(messageID)
+	if !ok {
+		msg = Message{ID: messageID, ChatID: c.ID}
+	}
+
+	id := fmt.Sprintf("cb%d", atomic.AddInt64(&c.nextUpdateID, 1))
+	callback := &tgbotapi.CallbackQuery{
+		ID:   id,
+		From: c.user(username),
+		Message: &tgbotapi.Message{
+			MessageID:   msg.ID,
+			Chat:        &tgbotapi.Chat{ID: c.ID},
+			Text:        msg.Text,
+			ReplyMarkup: msg.ReplyMarkup,
+		},
+		Data: data,
+	}
+	c.dispatch(tgbotapi.Update{CallbackQuery: callback})
+	return id
+}
+
+// PressButton taps the button with the given label on a bot message
+func (c *Chat) PressButton(username string, messageID int, label string) (string, error) {
+	msg, ok := c.Server.Message(messageID)
+	if !ok {
+		return "", fmt.Errorf("message %d is not visible", messageID)
+	}
+	data, ok := msg.Button(label)
+	if !ok {
+		return "", fmt.Errorf("message %d has no button %q", messageID, label)
+	}
+	return c.Press(username, messageID, data), nil
+}
+
+func (c *Chat) newMessage(username, text string) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		MessageID: c.Server.postUserMessage(),
+		From:      c.user(username),
+		Chat:      &tgbotapi.Chat{ID: c.ID, Type: "group"},
+		Date:      int(time.Now().Unix()),
+		Text:      text,
+		Entities:  commandEntities(text),
+	}
+}
+
+func (c *Chat) user(username string) *tgbotapi.User {
+	if user, ok := c.users[username]; ok {
+		return user
+	}
+	user := &tgbotapi.User{ID: int64(len(c.users) + 100), FirstName: username, UserName: username}
+	c.users[username] = user
+	return user
+}
+
+func (c *Chat) dispatch(update tgbotapi.Update) {
+	update.UpdateID = int(atomic.AddInt64(&c.nextUpdateID, 1))
+	c.Handle(update)
+}
+
+// commandEntities marks a leading /command the way Telegram does
+func commandEntities(text string) []tgbotapi.MessageEntity {
+	if !strings.HasPrefix(text, "/") {
+		return nil
+	}
+	length := strings.IndexAny(text, " \n")
+	if length < 0 {
+		length = len(text)
+	}
+	return []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+}
@@ -0,0 +1,383 @@
+// Package telegramtest provides a local stand-in for the Telegram Bot API so
+// handlers can be driven end to end without network access.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// BotUserName is the username reported by getMe
+const BotUserName = "expense_test_bot"
+
+// Call is a single Bot API request received by the server
+type Call struct {
+	Method string
+	Params map[string]string
+	Files  map[string][]byte
+}
+
+// Message is a bot message as it currently appears in the chat
+type Message struct {
+	ID          int
+	ChatID      int64
+	Text        string
+	ParseMode   string
+	ReplyMarkup *tgbotapi.InlineKeyboardMarkup
+	Document    string
+	Caption     string
+}
+
+// Button returns the callback data of the button with the given label
+func (m *Message) Button(label string) (string, bool) {
+	if m.ReplyMarkup == nil {
+		return "", false
+	}
+	for _, row := range m.ReplyMarkup.InlineKeyboard {
+		for _, btn := range row {
+			if btn.Text == label && btn.CallbackData != nil {
+				return *btn.CallbackData, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Server records Bot API calls and keeps track of the messages the bot
+// has sent, edited and deleted
+type Server struct {
+	srv *httptest.Server
+
+	mu            sync.Mutex
+	nextMessageID int
+	calls         []Call
+	messages      map[int]*Message
+	userMessages  map[int]bool
+	answers       map[string]string
+}
+
+// NewServer starts a fake Bot API server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		nextMessageID: 1,
+		messages:      make(map[int]*Message),
+		userMessages:  make(map[int]bool),
+		answers:       make(map[string]string),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Endpoint returns the API endpoint format for tgbotapi.NewBotAPIWithAPIEndpoint
+func (s *Server) Endpoint() string {
+	return s.srv.URL + "/bot%s/%s"
+}
+
+// NewBot creates a bot client that talks to this server
+func (s *Server) NewBot() (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithAPIEndpoint("test-token", s.Endpoint())
+}
+
+// NextMessageID reserves the next message ID in the chat, shared by user
+// and bot messages just like in Telegram
+func (s *Server) NextMessageID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextMessageID
+	s.nextMessageID++
+	return id
+}
+
+// postUserMessage reserves the ID of a message sent by a user and keeps it
+// until DeleteUserMessage
+func (s *Server) postUserMessage() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.nextMessageID
+	s.nextMessageID++
+	s.userMessages[id] = true
+	return id
+}
+
+// DeleteUserMessage removes a user message from the chat. Like Telegram,
+// the server sends no update about it; copyMessage starts failing.
+func (s *Server) DeleteUserMessage(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.userMessages, id)
+}
+
+// Calls returns every request received so far, optionally filtered by method
+func (s *Server) Calls(methods ...string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+	for _, call := range s.calls {
+		if len(methods) == 0 || contains(methods, call.Method) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// ResetCalls forgets the recorded calls but keeps the chat state
+func (s *Server) ResetCalls() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// Messages returns the bot messages that are still visible, oldest first
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var messages []Message
+	for _, msg := range s.messages {
+		messages = append(messages, *msg)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages
+}
+
+// Message returns a visible bot message by ID
+func (s *Server) Message(id int) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[id]
+	if !ok {
+		return Message{}, false
+	}
+	return *msg, true
+}
+
+// LastMessage returns the most recently sent visible bot message
+func (s *Server) LastMessage() (Message, bool) {
+	messages := s.Messages()
+	if len(messages) == 0 {
+		return Message{}, false
+	}
+	return messages[len(messages)-1], true
+}
+
+// CallbackAnswer returns the text the bot answered a callback query with
+func (s *Server) CallbackAnswer(callbackID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	text, ok := s.answers[callbackID]
+	return text, ok
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	// Paths look like /bot<token>/<method>
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "bot") {
+		http.NotFound(w, r)
+		return
+	}
+	method := parts[1]
+
+	call, err := parseCall(method, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	result, status, errText := s.apply(call)
+	s.mu.Unlock()
+
+	if errText != "" {
+		writeError(w, status, errText)
+		return
+	}
+	writeResult(w, result)
+}
+
+// apply updates the chat state for a call and builds its result. Callers
+// must hold s.mu.
+func (s *Server) apply(call Call) (interface{}, int, string) {
+	p := call.Params
+	chatID, _ := strconv.ParseInt(p["chat_id"], 10, 64)
+	messageID, _ := strconv.Atoi(p["message_id"])
+
+	switch call.Method {
+	case "getMe":
+		return tgbotapi.User{ID: 1, IsBot: true, FirstName: "Expense Bot", UserName: BotUserName}, 0, ""
+
+	case "sendMessage", "sendDocument":
+		msg := &Message{
+			ID:        s.nextMessageID,
+			ChatID:    chatID,
+			Text:      p["text"],
+			ParseMode: p["parse_mode"],
+			Caption:   p["caption"],
+		}
+		s.nextMessageID++
+		msg.ReplyMarkup = parseMarkup(p["reply_markup"])
+		for name := range call.Files {
+			msg.Document = name
+		}
+		s.messages[msg.ID] = msg
+		return s.toAPIMessage(msg), 0, ""
+
+	case "editMessageText", "editMessageReplyMarkup":
+		msg, ok := s.messages[messageID]
+		if !ok {
+			return nil, http.StatusBadRequest, "Bad Request: message to edit not found"
+		}
+		if call.Method == "editMessageText" {
+			msg.Text = p["text"]
+			msg.ParseMode = p["parse_mode"]
+		}
+		msg.ReplyMarkup = parseMarkup(p["reply_markup"])
+		return s.toAPIMessage(msg), 0, ""
+
+	case "copyMessage":
+		original, ok := s.messages[messageID]
+		if !ok && !s.userMessages[messageID] {
+			return nil, http.StatusBadRequest, "Bad Request: message to copy not found"
+		}
+		msg := &Message{ID: s.nextMessageID, ChatID: chatID}
+		if original != nil {
+			msg.Text = original.Text
+		}
+		s.nextMessageID++
+		s.messages[msg.ID] = msg
+		return tgbotapi.MessageID{MessageID: msg.ID}, 0, ""
+
+	case "deleteMessage":
+		if _, ok := s.messages[messageID]; !ok {
+			return nil, http.StatusBadRequest, "Bad Request: message to delete not found"
+		}
+		delete(s.messages, messageID)
+		return true, 0, ""
+
+	case "answerCallbackQuery":
+		s.answers[p["callback_query_id"]] = p["text"]
+		return true, 0, ""
+
+	default:
+		return true, 0, ""
+	}
+}
+
+func (s *Server) toAPIMessage(msg *Message) tgbotapi.Message {
+	result := tgbotapi.Message{
+		MessageID:   msg.ID,
+		From:        &tgbotapi.User{ID: 1, IsBot: true, UserName: BotUserName},
+		Chat:        &tgbotapi.Chat{ID: msg.ChatID},
+		Text:        msg.Text,
+		Caption:     msg.Caption,
+		ReplyMarkup: msg.ReplyMarkup,
+	}
+	if msg.Document != "" {
+		result.Document = &tgbotapi.Document{FileName: msg.Document}
+	}
+	return result
+}
+
+// parseCall reads the form or multipart parameters of a request
+func parseCall(method string, r *http.Request) (Call, error) {
+	call := Call{Method: method, Params: make(map[string]string), Files: make(map[string][]byte)}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return call, err
+		}
+		for key, values := range r.MultipartForm.Value {
+			call.Params[key] = values[0]
+		}
+		for _, headers := range r.MultipartForm.File {
+			for _, header := range headers {
+				f, err := header.Open()
+				if err != nil {
+					return call, err
+				}
+				data, err := io.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return call, err
+				}
+				call.Files[header.Filename] = data
+			}
+		}
+		return call, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return call, err
+	}
+	for key, values := range r.PostForm {
+		call.Params[key] = values[0]
+	}
+	return call, nil
+}
+
+func parseMarkup(raw string) *tgbotapi.InlineKeyboardMarkup {
+	if raw == "" {
+		return nil
+	}
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(raw), &markup); err != nil || markup.InlineKeyboard == nil {
+		return nil
+	}
+	return &markup
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: raw})
+}
+
+func writeError(w http.ResponseWriter, status int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: false, ErrorCode: status, Description: description})
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders a call for failure messages
+func (c Call) String() string {
+	keys := make([]string, 0, len(c.Params))
+	for key := range c.Params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(c.Method)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%q", key, c.Params[key])
+	}
+	return b.String()
+}
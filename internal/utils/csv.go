@@ -4,32 +4,38 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
-	"math"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"telegram-expense-bot/internal/models"
 )
 
-// GenerateMonthlyCSV creates a CSV file content for monthly data
-func GenerateMonthlyCSV(archive *models.MonthlyArchive, writer io.Writer) error {
+// GeneratePeriodCSV creates a CSV file content for an archived period of
+// the cycle
+func GeneratePeriodCSV(archive *models.MonthlyArchive, cycle models.Cycle, writer io.Writer) error {
 	csvWriter := csv.NewWriter(writer)
 	defer csvWriter.Flush()
 
 	// Header section
 	header := [][]string{
-		{"Monthly Expense Report"},
-		{"Month", archive.MonthName + " " + strconv.Itoa(archive.Year)},
+		{Capitalize(cycle.Adjective()) + " Expense Report"},
+		{"Period", archive.PeriodLabel()},
 		{"Generated", time.Now().Format("2006-01-02 15:04:05")},
 		{}, // Empty row
 		{"SUMMARY"},
-		{"Total Spent", fmt.Sprintf("%.2f", archive.TotalSpent)},
-		{"Total Transactions", strconv.Itoa(archive.TotalTransactions)},
-		{"Average Transaction", fmt.Sprintf("%.2f", archive.AvgTransaction)},
-		{"Highest Transaction", fmt.Sprintf("%.2f", archive.HighestTransaction)},
-		{"Lowest Transaction", fmt.Sprintf("%.2f", archive.LowestTransaction)},
+		{"Total Spent", archive.TotalSpent.String()},
+		{"Expenses", strconv.Itoa(archive.TotalTransactions)},
+		{"Average Expense", archive.AvgTransaction.String()},
+		{"Highest Expense", archive.HighestTransaction.String()},
+		{"Lowest Expense", archive.LowestTransaction.String()},
+		{"Transactions", strconv.Itoa(len(archive.Transactions))},
 		{"Days with Spending", strconv.Itoa(archive.DaysWithSpending)},
-		{"Balance", fmt.Sprintf("%.2f", archive.Balance)},
+		{"Outstanding Balance", archive.Balance.String()},
+		{"Total Refunded", archive.TotalRefunded.String()},
+		{"Total Income", archive.TotalIncome.String()},
+		{"Total Settled", archive.TotalSettled.String()},
 		{}, // Empty row
 	}
 
@@ -49,11 +55,18 @@ func GenerateMonthlyCSV(archive *models.MonthlyArchive, writer io.Writer) error
 			return err
 		}
 		
-		for user, amount := range archive.UserTotals {
-			percentage := (amount / archive.TotalSpent) * 100
+		users := make([]string, 0, len(archive.UserTotals))
+		for user := range archive.UserTotals {
+			users = append(users, user)
+		}
+		sort.Strings(users)
+
+		for _, user := range users {
+			amount := archive.UserTotals[user]
+			percentage := (amount.Float() / archive.TotalSpent.Float()) * 100
 			row := []string{
 				user,
-				fmt.Sprintf("%.2f", amount),
+				amount.String(),
 				fmt.Sprintf("%.1f%%", percentage),
 			}
 			if err := csvWriter.Write(row); err != nil {
@@ -65,6 +78,31 @@ func GenerateMonthlyCSV(archive *models.MonthlyArchive, writer io.Writer) error
 		}
 	}
 
+	// Member balances section
+	if len(archive.Balances) > 0 {
+		if err := csvWriter.Write([]string{"BALANCES"}); err != nil {
+			return err
+		}
+		if err := csvWriter.Write([]string{"User", "Paid", "Share", "Net"}); err != nil {
+			return err
+		}
+
+		for _, b := range archive.Balances {
+			row := []string{
+				b.User,
+				b.Paid.String(),
+				b.Share.String(),
+				b.Net.String(),
+			}
+			if err := csvWriter.Write(row); err != nil {
+				return err
+			}
+		}
+		if err := csvWriter.Write([]string{}); err != nil {
+			return err
+		}
+	}
+
 	// Category totals section
 	if len(archive.CategoryTotals) > 0 {
 		if err := csvWriter.Write([]string{"CATEGORY BREAKDOWN"}); err != nil {
@@ -75,10 +113,10 @@ func GenerateMonthlyCSV(archive *models.MonthlyArchive, writer io.Writer) error
 		}
 		
 		for category, amount := range archive.CategoryTotals {
-			percentage := (amount / archive.TotalSpent) * 100
+			percentage := (amount.Float() / archive.TotalSpent.Float()) * 100
 			row := []string{
 				category,
-				fmt.Sprintf("%.2f", amount),
+				amount.String(),
 				fmt.Sprintf("%.1f%%", percentage),
 			}
 			if err := csvWriter.Write(row); err != nil {
@@ -95,23 +133,44 @@ func GenerateMonthlyCSV(archive *models.MonthlyArchive, writer io.Writer) error
 		if err := csvWriter.Write([]string{"DETAILED TRANSACTIONS"}); err != nil {
 			return err
 		}
-		if err := csvWriter.Write([]string{"Date", "Time", "Amount", "Author", "Category"}); err != nil {
+		if err := csvWriter.Write([]string{"Date", "Time", "Type", "Amount", "Original Amount", "Currency", "Rate", "Payer", "Recipient", "Beneficiaries", "Category", "Split", "Note", "Logged By"}); err != nil {
 			return err
 		}
 		
 		for _, tx := range archive.Transactions {
 			date := time.Unix(tx.CreatedAt, 0)
+			kind := tx.Kind
+			if kind == "" {
+				kind = models.KindExpense
+			}
 			category := tx.Category
-			if category == "" {
+			if category == "" && tx.HasCategory() {
 				category = "Uncategorized"
 			}
 			
 			row := []string{
 				date.Format("2006-01-02"),
 				date.Format("15:04:05"),
-				fmt.Sprintf("%.2f", math.Abs(tx.Amount)),
-				tx.Author,
+				kind,
+				tx.HomeAmount().String(),
+				"",
+				"",
+				"",
+				tx.PaidBy(),
+				tx.To,
+				strings.Join(tx.Beneficiaries, "; "),
 				category,
+				"",
+				tx.Note,
+				tx.Author,
+			}
+			if tx.HasCategory() || tx.Kind == models.KindIncome {
+				row[11] = DescribeSplit(tx.Split)
+			}
+			if tx.Currency != "" {
+				row[4] = tx.Amount.Abs().String()
+				row[5] = tx.Currency
+				row[6] = strconv.FormatFloat(tx.Rate, 'f', -1, 64)
 			}
 			if err := csvWriter.Write(row); err != nil {
 				return err
@@ -122,8 +181,9 @@ func GenerateMonthlyCSV(archive *models.MonthlyArchive, writer io.Writer) error
 	return nil
 }
 
-// GenerateComparisonCSV creates a comparison CSV for multiple months
-func GenerateComparisonCSV(archives []models.MonthlyArchive, writer io.Writer) error {
+// GenerateComparisonCSV creates a comparison CSV for archived periods of
+// the cycle
+func GenerateComparisonCSV(archives []models.MonthlyArchive, cycle models.Cycle, writer io.Writer) error {
 	if len(archives) == 0 {
 		return fmt.Errorf("no archives provided for comparison")
 	}
@@ -133,7 +193,7 @@ func GenerateComparisonCSV(archives []models.MonthlyArchive, writer io.Writer) e
 
 	// Header
 	header := [][]string{
-		{"Monthly Comparison Report"},
+		{Capitalize(cycle.Adjective()) + " Comparison Report"},
 		{"Generated", time.Now().Format("2006-01-02 15:04:05")},
 		{}, // Empty row
 	}
@@ -147,7 +207,7 @@ func GenerateComparisonCSV(archives []models.MonthlyArchive, writer io.Writer) e
 	// Summary comparison
 	summaryHeader := []string{"Metric"}
 	for _, archive := range archives {
-		summaryHeader = append(summaryHeader, archive.MonthName+" "+strconv.Itoa(archive.Year))
+		summaryHeader = append(summaryHeader, archive.PeriodLabel())
 	}
 	if err := csvWriter.Write(summaryHeader); err != nil {
 		return err
@@ -156,10 +216,10 @@ func GenerateComparisonCSV(archives []models.MonthlyArchive, writer io.Writer) e
 	// Write metrics rows
 	metrics := []string{
 		"Total Spent",
-		"Total Transactions", 
-		"Average Transaction",
-		"Highest Transaction",
-		"Lowest Transaction",
+		"Expenses",
+		"Average Expense",
+		"Highest Expense",
+		"Lowest Expense",
 		"Days with Spending",
 		"Balance",
 	}
@@ -170,19 +230,19 @@ func GenerateComparisonCSV(archives []models.MonthlyArchive, writer io.Writer) e
 			var value string
 			switch metric {
 			case "Total Spent":
-				value = fmt.Sprintf("%.2f", archive.TotalSpent)
-			case "Total Transactions":
+				value = archive.TotalSpent.String()
+			case "Expenses":
 				value = strconv.Itoa(archive.TotalTransactions)
-			case "Average Transaction":
-				value = fmt.Sprintf("%.2f", archive.AvgTransaction)
-			case "Highest Transaction":
-				value = fmt.Sprintf("%.2f", archive.HighestTransaction)
-			case "Lowest Transaction":
-				value = fmt.Sprintf("%.2f", archive.LowestTransaction)
+			case "Average Expense":
+				value = archive.AvgTransaction.String()
+			case "Highest Expense":
+				value = archive.HighestTransaction.String()
+			case "Lowest Expense":
+				value = archive.LowestTransaction.String()
 			case "Days with Spending":
 				value = strconv.Itoa(archive.DaysWithSpending)
 			case "Balance":
-				value = fmt.Sprintf("%.2f", archive.Balance)
+				value = archive.Balance.String()
 			}
 			row = append(row, value)
 		}
@@ -191,38 +251,39 @@ func GenerateComparisonCSV(archives []models.MonthlyArchive, writer io.Writer) e
 		}
 	}
 
-	// Add growth rates if we have multiple months
+	// Add growth rates if we have multiple periods
 	if len(archives) > 1 {
 		if err := csvWriter.Write([]string{}); err != nil {
 			return err
 		}
-		if err := csvWriter.Write([]string{"GROWTH RATES (Month-over-Month)"}); err != nil {
+		noun := Capitalize(cycle.Noun())
+		if err := csvWriter.Write([]string{fmt.Sprintf("GROWTH RATES (%s-over-%s)", noun, noun)}); err != nil {
 			return err
 		}
 		
 		growthHeader := []string{"Metric"}
 		for i := 1; i < len(archives); i++ {
 			growthHeader = append(growthHeader, fmt.Sprintf("%s vs %s", 
-				archives[i].MonthName, archives[i-1].MonthName))
+				archives[i].PeriodLabel(), archives[i-1].PeriodLabel()))
 		}
 		if err := csvWriter.Write(growthHeader); err != nil {
 			return err
 		}
 
-		for _, metric := range []string{"Total Spent", "Total Transactions", "Average Transaction"} {
+		for _, metric := range []string{"Total Spent", "Expenses", "Average Expense"} {
 			row := []string{metric}
 			for i := 1; i < len(archives); i++ {
 				var current, previous float64
 				switch metric {
 				case "Total Spent":
-					current = archives[i].TotalSpent
-					previous = archives[i-1].TotalSpent
-				case "Total Transactions":
+					current = archives[i].TotalSpent.Float()
+					previous = archives[i-1].TotalSpent.Float()
+				case "Expenses":
 					current = float64(archives[i].TotalTransactions)
 					previous = float64(archives[i-1].TotalTransactions)
-				case "Average Transaction":
-					current = archives[i].AvgTransaction
-					previous = archives[i-1].AvgTransaction
+				case "Average Expense":
+					current = archives[i].AvgTransaction.Float()
+					previous = archives[i-1].AvgTransaction.Float()
 				}
 				
 				var growth string
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"telegram-expense-bot/internal/models"
+)
+
+func TestCSVNamesTheCycle(t *testing.T) {
+	archives := []models.MonthlyArchive{
+		{ID: "2025-03-10", Label: "Mar 10 – Mar 16, 2025", TotalSpent: 3000, TotalTransactions: 2},
+		{ID: "2025-03-03", Label: "Mar 3 – Mar 9, 2025", TotalSpent: 1500, TotalTransactions: 1},
+	}
+	weekly := models.Cycle{Length: models.CycleWeekly}
+
+	tests := []struct {
+		name     string
+		generate func(*bytes.Buffer) error
+		want     []string
+	}{
+		{
+			name:     "period",
+			generate: func(buf *bytes.Buffer) error { return GeneratePeriodCSV(&archives[0], weekly, buf) },
+			want:     []string{"Weekly Expense Report", "Period,\"Mar 10 – Mar 16, 2025\"", "Expenses,2"},
+		},
+		{
+			name:     "comparison",
+			generate: func(buf *bytes.Buffer) error { return GenerateComparisonCSV(archives, weekly, buf) },
+			want:     []string{"Weekly Comparison Report", "GROWTH RATES (Week-over-Week)", "Expenses,2,1"},
+		},
+		{
+			name:     "monthly by default",
+			generate: func(buf *bytes.Buffer) error { return GenerateComparisonCSV(archives, models.Cycle{}, buf) },
+			want:     []string{"Monthly Comparison Report", "GROWTH RATES (Month-over-Month)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.generate(&buf); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Expected %q in:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"telegram-expense-bot/internal/models"
+)
+
+// currencySymbols maps the symbols accepted in front of or after an amount
+// to their currency codes. "$" is resolved against the home currency.
+var currencySymbols = map[string]string{
+	"$": "$",
+	"€": "EUR",
+	"£": "GBP",
+	"¥": "JPY",
+	"₹": "INR",
+	"₩": "KRW",
+	"₪": "ILS",
+	"₺": "TRY",
+	"₴": "UAH",
+	"₽": "RUB",
+}
+
+// currencyCodes are the ISO 4217 codes recognised after an amount
+var currencyCodes = map[string]bool{
+	"AUD": true, "BRL": true, "CAD": true, "CHF": true, "CNY": true, "CZK": true,
+	"DKK": true, "EUR": true, "GBP": true, "HKD": true, "HUF": true, "ILS": true,
+	"INR": true, "ISK": true, "JPY": true, "KRW": true, "MXN": true, "NOK": true,
+	"NZD": true, "PLN": true, "RUB": true, "SEK": true, "SGD": true, "THB": true,
+	"TRY": true, "UAH": true, "USD": true, "ZAR": true,
+}
+
+// dollarCurrencies are written with a plain "$"
+var dollarCurrencies = map[string]bool{
+	"AUD": true, "CAD": true, "HKD": true, "MXN": true, "NZD": true, "SGD": true, "USD": true,
+}
+
+// IsCurrencyCode reports whether word is a recognised currency code
+func IsCurrencyCode(word string) bool {
+	return currencyCodes[strings.ToUpper(word)]
+}
+
+// ParseAmountToken parses an amount with an optional currency symbol or
+// code attached, like "25", "12,99", "€25", "25€", "25EUR" or "€12+8"
+func ParseAmountToken(token string) (models.Money, string, error) {
+	token, currency := splitCurrency(token)
+	amount, err := ValidateAmount(token)
+	if err != nil {
+		return 0, "", err
+	}
+	return amount, currency, nil
+}
+
+// splitCurrency separates a currency symbol or code attached to an amount
+func splitCurrency(token string) (string, string) {
+	for symbol, code := range currencySymbols {
+		if strings.HasPrefix(token, symbol) {
+			return strings.TrimPrefix(token, symbol), code
+		}
+		if strings.HasSuffix(token, symbol) {
+			return strings.TrimSuffix(token, symbol), code
+		}
+	}
+	if i := strings.IndexFunc(token, unicode.IsLetter); i > 0 && IsCurrencyCode(token[i:]) {
+		return token[:i], strings.ToUpper(token[i:])
+	}
+	return token, ""
+}
+
+// ResolveCurrency returns the currency code to store for what the user
+// wrote, or "" for the home currency
+func ResolveCurrency(written, home string) string {
+	code := strings.ToUpper(written)
+	if code == "$" {
+		code = "USD"
+		if dollarCurrencies[home] {
+			code = home
+		}
+	}
+	if code == home {
+		return ""
+	}
+	return code
+}
+
+// FormatMoney formats an amount in the given currency, e.g. "25.00€" or
+// "25.00 USD"
+func FormatMoney(amount models.Money, currency string) string {
+	for symbol, code := range currencySymbols {
+		if code == currency {
+			return amount.String() + symbol
+		}
+	}
+	return amount.String() + " " + currency
+}
+
+// FormatHome formats an amount in the home currency. Dollar currencies keep
+// the plain "$" the bot has always shown.
+func FormatHome(amount models.Money, home string) string {
+	if home == "" || dollarCurrencies[home] {
+		return amount.String() + "$"
+	}
+	return FormatMoney(amount, home)
+}
+
+// FormatTransactionAmount formats a transaction's amount in the home
+// currency, with the original amount in front when it was entered in
+// another currency
+func FormatTransactionAmount(tx *models.Transaction, home string) string {
+	if tx.Currency == "" {
+		return FormatHome(tx.HomeAmount(), home)
+	}
+	return fmt.Sprintf("%s (%s)", FormatMoney(tx.Amount.Abs(), tx.Currency), FormatHome(tx.HomeAmount(), home))
+}
+
+// LoadRates reads a rates file: a JSON object with the value of one unit of
+// each currency in the home currency, e.g. {"EUR": 1.49, "USD": 1.37}
+func LoadRates(path string) (map[string]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var raw map[string]float64
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+
+	rates := make(map[string]float64, len(raw))
+	for code, rate := range raw {
+		if rate > 0 {
+			rates[strings.ToUpper(code)] = rate
+		}
+	}
+	return rates, nil
+}
+
+// LookupRate returns the rate for a currency from the rates file. The file
+// is read on every lookup so edits apply without a restart.
+func LookupRate(path, currency string) (float64, error) {
+	if path == "" {
+		return 0, fmt.Errorf("no exchange rate for %s", currency)
+	}
+	rates, err := LoadRates(path)
+	if err != nil {
+		return 0, err
+	}
+	rate, ok := rates[currency]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", currency)
+	}
+	return rate, nil
+}
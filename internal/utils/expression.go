@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IsExpression reports whether an amount is written as arithmetic, like
+// "12.50+8.99+3" or "100/3", rather than as a single number
+func IsExpression(text string) bool {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "(") {
+		return true
+	}
+	// A leading sign alone doesn't make an expression
+	return len(text) > 1 && strings.ContainsAny(text[1:], "+-*/()")
+}
+
+// EvaluateExpression evaluates + - * / and parentheses over decimal
+// numbers written as normalizeNumber accepts them
+func EvaluateExpression(text string) (float64, error) {
+	p := &exprParser{text: strings.ReplaceAll(text, " ", "")}
+	if p.text == "" {
+		return 0, fmt.Errorf("empty expression")
+	}
+	value, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos < len(p.text) {
+		return 0, fmt.Errorf("unexpected %q in expression", p.text[p.pos])
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("expression is out of range")
+	}
+	return value, nil
+}
+
+// maxExprDepth bounds nested parentheses and unary signs
+const maxExprDepth = 32
+
+// exprParser is a recursive descent parser over
+//
+//	sum     = product { ("+" | "-") product }
+//	product = factor { ("*" | "/") factor }
+//	factor  = ("+" | "-") factor | "(" sum ")" | number
+type exprParser struct {
+	text  string
+	pos   int
+	depth int
+}
+
+func (p *exprParser) sum() (float64, error) {
+	value, err := p.product()
+	if err != nil {
+		return 0, err
+	}
+	for p.pos < len(p.text) && (p.text[p.pos] == '+' || p.text[p.pos] == '-') {
+		op := p.text[p.pos]
+		p.pos++
+		right, err := p.product()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			value += right
+		} else {
+			value -= right
+		}
+	}
+	return value, nil
+}
+
+func (p *exprParser) product() (float64, error) {
+	value, err := p.factor()
+	if err != nil {
+		return 0, err
+	}
+	for p.pos < len(p.text) && (p.text[p.pos] == '*' || p.text[p.pos] == '/') {
+		op := p.text[p.pos]
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return 0, err
+		}
+		if op == '*' {
+			value *= right
+		} else {
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			value /= right
+		}
+	}
+	return value, nil
+}
+
+func (p *exprParser) factor() (float64, error) {
+	if p.pos >= len(p.text) {
+		return 0, fmt.Errorf("expression ends too early")
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExprDepth {
+		return 0, fmt.Errorf("expression is nested too deeply")
+	}
+
+	switch p.text[p.pos] {
+	case '+', '-':
+		sign := 1.0
+		if p.text[p.pos] == '-' {
+			sign = -1
+		}
+		p.pos++
+		value, err := p.factor()
+		return sign * value, err
+	case '(':
+		p.pos++
+		value, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.pos >= len(p.text) || p.text[p.pos] != ')' {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return value, nil
+	}
+	return p.number()
+}
+
+func (p *exprParser) number() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.text) && strings.IndexByte("0123456789.,", p.text[p.pos]) >= 0 {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, fmt.Errorf("unexpected %q in expression", p.text[p.pos])
+	}
+
+	value, err := strconv.ParseFloat(normalizeNumber(p.text[start:p.pos]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q in expression", p.text[start:p.pos])
+	}
+	return value, nil
+}
+
+// normalizeNumber rewrites a number with a decimal comma or thousands
+// separators into the plain form strconv reads. When a number has both a
+// comma and a point, the last one is the decimal separator ("1.234,50",
+// "1,234.50"). A separator used more than once between groups of three
+// digits, or a single comma before exactly three digits, groups thousands
+// ("1,000", "1.000.000"); any other comma is a decimal comma ("12,99").
+func normalizeNumber(token string) string {
+	commas, points := strings.Count(token, ","), strings.Count(token, ".")
+	switch {
+	case commas > 0 && points > 0:
+		last := strings.LastIndexAny(token, ".,")
+		whole := strings.NewReplacer(".", "", ",", "").Replace(token[:last])
+		return whole + "." + token[last+1:]
+	case commas > 1 || points > 1:
+		groups := strings.FieldsFunc(token, func(r rune) bool { return r == ',' || r == '.' })
+		for _, group := range groups[1:] {
+			if len(group) != 3 {
+				return token // Not thousands, e.g. a date like "14.03.2025"
+			}
+		}
+		return strings.Join(groups, "")
+	case commas == 1:
+		whole, fraction, _ := strings.Cut(token, ",")
+		if len(fraction) == 3 {
+			return whole + fraction
+		}
+		return whole + "." + fraction
+	}
+	return token
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIsExpression(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"25", false},
+		{"25.50", false},
+		{"-25", false},
+		{"12.50+8.99+3", true},
+		{"100/3", true},
+		{"(10+5)", true},
+		{" 3*4 ", true},
+		{"+", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsExpression(tt.text); got != tt.want {
+			t.Errorf("IsExpression(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    float64
+		wantErr bool
+	}{
+		{text: "12.50+8.99+3", want: 24.49},
+		{text: "100/3", want: 100.0 / 3},
+		{text: "2+3*4", want: 14},
+		{text: "(2+3)*4", want: 20},
+		{text: "10-4-3", want: 3},
+		{text: "24/4/2", want: 3},
+		{text: "-5+10", want: 5},
+		{text: "2*-3", want: -6},
+		{text: "12,99+1", want: 13.99},
+		{text: "1.234,50*2", want: 2469},
+		{text: "1,000+1", want: 1001},
+		{text: " 1 + 2 ", want: 3},
+		{text: "", wantErr: true},
+		{text: "1/0", wantErr: true},
+		{text: "1+", wantErr: true},
+		{text: "(1+2", wantErr: true},
+		{text: "1+2)", wantErr: true},
+		{text: "2x3", wantErr: true},
+		{text: "1.2.3+1", wantErr: true},
+		{text: "((((((((((((((((((((((((((((((((((1))))))))))))))))))))))))))))))))))", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := EvaluateExpression(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("EvaluateExpression(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EvaluateExpression(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNumber(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"25", "25"},
+		{"12.99", "12.99"},
+		{"12,99", "12.99"},
+		{"12,5", "12.5"},
+		{"1,000", "1000"},
+		{"1.000.000", "1000000"},
+		{"1,234.50", "1234.50"},
+		{"1.234,50", "1234.50"},
+		{"14.03.2025", "14.03.2025"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeNumber(tt.token); got != tt.want {
+			t.Errorf("normalizeNumber(%q) = %q, want %q", tt.token, got, tt.want)
+		}
+	}
+}
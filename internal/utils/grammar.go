@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseOptions is what the transaction parser needs to know about the chat
+type ParseOptions struct {
+	Categories []string          // Category labels, e.g. "Groceries 🛒"
+	Aliases    map[string]string // Lowercase alias to category label
+	Now        time.Time         // Reference time for relative dates
+}
+
+// weekdays maps the weekday words accepted as dates
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// ParseDate parses a date word: "today", "yesterday", a weekday name
+// (the most recent one before today), "2025-03-14" or "14.03.2025". The
+// result keeps the time of day of now. ok is false when word is not a date.
+func ParseDate(word string, now time.Time) (time.Time, bool, error) {
+	word = strings.ToLower(word)
+	switch word {
+	case "today":
+		return now, true, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), true, nil
+	}
+	if day, found := weekdays[word]; found {
+		back := (int(now.Weekday()) - int(day) + 7) % 7
+		if back == 0 {
+			back = 7
+		}
+		return now.AddDate(0, 0, -back), true, nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "2.1.2006"} {
+		date, err := time.ParseInLocation(layout, word, now.Location())
+		if err != nil {
+			continue
+		}
+		date = time.Date(date.Year(), date.Month(), date.Day(), now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+		if date.After(now) {
+			return time.Time{}, true, fmt.Errorf("%s is in the future", word)
+		}
+		return date, true, nil
+	}
+	return time.Time{}, false, nil
+}
+
+// MatchCategory finds the category a word refers to. A word matches a
+// category by its name ("groceries", "grocery", "dining", "lcbo") or by a
+// configured alias ("costco"). byAlias reports an alias match, since an
+// alias is usually a merchant worth keeping in the note.
+func MatchCategory(word string, opts ParseOptions) (category string, byAlias bool, ok bool) {
+	lower := strings.ToLower(strings.Trim(word, ",.;:!?"))
+	if lower == "" {
+		return "", false, false
+	}
+	if category, found := opts.Aliases[lower]; found {
+		return category, true, true
+	}
+
+	stem := singular(lower)
+	for _, category := range opts.Categories {
+		words := strings.Fields(strings.ToLower(CategoryName(category)))
+		if len(words) == 0 {
+			continue
+		}
+		if stem == singular(strings.Join(words, "")) || stem == singular(words[0]) {
+			return category, false, true
+		}
+	}
+	return "", false, false
+}
+
+// CategoryName strips the trailing emoji from a category label
+func CategoryName(category string) string {
+	return strings.TrimRightFunc(category, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Capitalize upper-cases the first letter of a word, e.g. "Month"
+func Capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+// singular drops a plural ending so "groceries" and "grocery" match
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 3:
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
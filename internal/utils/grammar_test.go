@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	now := testOptions.Now
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+	}
+
+	tests := []struct {
+		word    string
+		want    time.Time
+		ok      bool
+		wantErr bool
+	}{
+		{word: "today", want: now, ok: true},
+		{word: "Yesterday", want: at(2025, time.March, 13), ok: true},
+		{word: "monday", want: at(2025, time.March, 10), ok: true},
+		{word: "fri", want: at(2025, time.March, 7), ok: true},
+		{word: "2025-03-01", want: at(2025, time.March, 1), ok: true},
+		{word: "01.03.2025", want: at(2025, time.March, 1), ok: true},
+		{word: "1.3.2025", want: at(2025, time.March, 1), ok: true},
+		{word: "2025-04-01", ok: true, wantErr: true},
+		{word: "groceries", ok: false},
+		{word: "25", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok, err := ParseDate(tt.word, now)
+		if ok != tt.ok || (err != nil) != tt.wantErr {
+			t.Errorf("ParseDate(%q) ok = %v, error = %v, want ok %v, wantErr %v", tt.word, ok, err, tt.ok, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %s, want %s", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestMatchCategory(t *testing.T) {
+	tests := []struct {
+		word     string
+		category string
+		byAlias  bool
+		ok       bool
+	}{
+		{"groceries", "Groceries 🛒", false, true},
+		{"Grocery", "Groceries 🛒", false, true},
+		{"groceries,", "Groceries 🛒", false, true},
+		{"dining", "Dining Out 🍽", false, true},
+		{"diningout", "Dining Out 🍽", false, true},
+		{"COSTCO", "Groceries 🛒", true, true},
+		{"taxi", "", false, false},
+		{"!", "", false, false},
+	}
+
+	for _, tt := range tests {
+		category, byAlias, ok := MatchCategory(tt.word, testOptions)
+		if category != tt.category || byAlias != tt.byAlias || ok != tt.ok {
+			t.Errorf("MatchCategory(%q) = %q, %v, %v, want %q, %v, %v",
+				tt.word, category, byAlias, ok, tt.category, tt.byAlias, tt.ok)
+		}
+	}
+}
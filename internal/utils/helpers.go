@@ -2,20 +2,36 @@ package utils
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+	"time"
+
+	"telegram-expense-bot/internal/models"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// ValidateAmount validates and parses amount from string
-func ValidateAmount(text string) (float64, error) {
+// ValidateAmount validates and parses amount from string. The amount may
+// use a decimal comma or be an arithmetic expression like "12.50+8.99+3"
+// or "100/3".
+func ValidateAmount(text string) (models.Money, error) {
 	text = strings.TrimSpace(text)
 	
-	// Try to parse as float
-	amount, err := strconv.ParseFloat(text, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid amount format")
+	// Parse to whole cents
+	var amount models.Money
+	if IsExpression(text) {
+		value, err := EvaluateExpression(text)
+		if err != nil {
+			return 0, err
+		}
+		amount = models.FromFloat(value)
+	} else {
+		var err error
+		amount, err = models.ParseMoney(normalizeNumber(text))
+		if err != nil {
+			return 0, err
+		}
 	}
 	
 	if amount <= 0 {
@@ -25,8 +41,212 @@ func ValidateAmount(text string) (float64, error) {
 	return amount, nil
 }
 
-// BuildInlineKeyboard builds inline keyboard for category selection
-func BuildInlineKeyboard(categories []string, messageID string) tgbotapi.InlineKeyboardMarkup {
+// parseRate parses a positive exchange rate
+func parseRate(text string) (float64, error) {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || rate <= 0 || math.IsInf(rate, 0) {
+		return 0, fmt.Errorf("invalid exchange rate")
+	}
+	return rate, nil
+}
+
+// ParsedTransaction is what a transaction message says
+type ParsedTransaction struct {
+	Kind          string
+	Amount        models.Money
+	Expression    string   // Arithmetic the amount was written as, "" for a plain number
+	Currency      string   // Currency code or symbol as written, "" when none was given
+	Rate          float64  // Exchange rate given with "at", 0 when none was given
+	To            string   // Recipient of a payment
+	Payer         string   // Who paid, when someone else did
+	Beneficiaries []string // Who it was for, when not everyone
+	Category      string    // Category named in the message, "" when none was
+	Note          string    // Words that are not part of the grammar
+	Date          time.Time // Date named in the message, zero when none was
+}
+
+// ParseTransaction parses a transaction message. Supported forms:
+//
+//	25.50              expense
+//	refund 15          refund
+//	income 200         shared income
+//	paid @lerotko 120  payment to another member (amount may come first)
+//
+// An amount may be arithmetic written without spaces, like "12.50+8.99+3".
+//
+// Any amount may carry a currency ("25 EUR", "€25") and a manual exchange
+// rate ("25 EUR at 1.47"). Expenses, refunds and income may be followed by
+// "@user paid" (or "paid by @user") and "for @user @user ...". Expenses
+// and refunds may name a category ("25.50 groceries costco"), and any
+// transaction may take a date ("yesterday", "2025-03-14") and a note made
+// of the remaining words.
+func ParseTransaction(text string, opts ParseOptions) (*ParsedTransaction, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("invalid amount format")
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "refund", "income":
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("usage: %s <amount>", strings.ToLower(fields[0]))
+		}
+		kind := models.KindRefund
+		if strings.ToLower(fields[0]) == "income" {
+			kind = models.KindIncome
+		}
+		parsed := &ParsedTransaction{Kind: kind}
+		if err := parseAmount(fields[1:], parsed, opts); err != nil {
+			return nil, err
+		}
+		return parsed, nil
+
+	case "paid":
+		usage := fmt.Errorf("usage: paid @user <amount>")
+		if len(fields) < 3 {
+			return nil, usage
+		}
+		// The recipient may come before or after the amount
+		var to string
+		var rest []string
+		for _, field := range fields[1:] {
+			if to == "" && isMention(field) {
+				to = field[1:]
+				continue
+			}
+			rest = append(rest, field)
+		}
+		if to == "" || len(rest) == 0 {
+			return nil, usage
+		}
+		parsed := &ParsedTransaction{Kind: models.KindSettlement, To: to}
+		if err := parseAmount(rest, parsed, opts); err != nil {
+			return nil, err
+		}
+		if parsed.Payer != "" || len(parsed.Beneficiaries) > 0 {
+			return nil, usage
+		}
+		return parsed, nil
+	}
+
+	parsed := &ParsedTransaction{Kind: models.KindExpense}
+	if err := parseAmount(fields, parsed, opts); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// parseAmount reads an amount with its optional currency, followed by any
+// modifiers
+func parseAmount(fields []string, parsed *ParsedTransaction, opts ParseOptions) error {
+	// "2025-03-14" would otherwise read as an expression
+	if _, isDate, _ := ParseDate(fields[0], opts.Now); isDate {
+		return fmt.Errorf("invalid amount format")
+	}
+	amount, currency, err := ParseAmountToken(fields[0])
+	if err != nil {
+		return err
+	}
+	if number, _ := splitCurrency(fields[0]); IsExpression(number) {
+		parsed.Expression = number
+	}
+	fields = fields[1:]
+	if currency == "" && len(fields) > 0 && IsCurrencyCode(fields[0]) {
+		currency = strings.ToUpper(fields[0])
+		fields = fields[1:]
+	}
+	parsed.Amount, parsed.Currency = amount, currency
+	return parseModifiers(fields, parsed, opts)
+}
+
+// parseModifiers reads what may follow an amount: an exchange rate
+// ("at 1.47"), the payer ("@user paid", "paid by @user"), the
+// beneficiaries ("for @user @user ..."), a category, a date and a note
+func parseModifiers(fields []string, parsed *ParsedTransaction, opts ParseOptions) error {
+	usage := fmt.Errorf("usage: <amount> [currency] [category] [note] [date] [at <rate>] [@user paid] [for @user ...]")
+	var note []string
+
+	for i := 0; i < len(fields); i++ {
+		word := strings.ToLower(fields[i])
+		switch {
+		case word == "at" && i+1 < len(fields) && parsed.Currency != "":
+			rate, err := parseRate(fields[i+1])
+			if err != nil {
+				return err
+			}
+			parsed.Rate = rate
+			i++
+
+		case isMention(word) && i+1 < len(fields) && strings.ToLower(fields[i+1]) == "paid":
+			parsed.Payer = fields[i][1:]
+			i++
+
+		case word == "paid" && i+2 < len(fields) && strings.ToLower(fields[i+1]) == "by" && isMention(fields[i+2]):
+			parsed.Payer = fields[i+2][1:]
+			i += 2
+
+		case word == "for" && i+1 < len(fields) && isMention(fields[i+1]):
+			for i+1 < len(fields) && isMention(fields[i+1]) {
+				user := strings.TrimSuffix(fields[i+1][1:], ",")
+				if !containsUser(parsed.Beneficiaries, user) {
+					parsed.Beneficiaries = append(parsed.Beneficiaries, user)
+				}
+				i++
+			}
+
+		case isMention(word):
+			return usage
+
+		default:
+			date, isDate, err := ParseDate(fields[i], opts.Now)
+			if err != nil {
+				return err
+			}
+			if isDate && parsed.Date.IsZero() {
+				parsed.Date = date
+				continue
+			}
+
+			if parsed.Category == "" && parsed.Kind != models.KindSettlement && parsed.Kind != models.KindIncome {
+				if category, byAlias, ok := MatchCategory(fields[i], opts); ok {
+					parsed.Category = category
+					if byAlias {
+						note = append(note, fields[i])
+						continue
+					}
+					// Skip the rest of a multi-word name like "dining out"
+					for _, rest := range strings.Fields(strings.ToLower(CategoryName(category)))[1:] {
+						if i+1 < len(fields) && strings.ToLower(fields[i+1]) == rest {
+							i++
+						}
+					}
+					continue
+				}
+			}
+			note = append(note, fields[i])
+		}
+	}
+	parsed.Note = strings.Join(note, " ")
+	return nil
+}
+
+// isMention reports whether a word is an @username
+func isMention(word string) bool {
+	return strings.HasPrefix(word, "@") && len(strings.TrimSuffix(word, ",")) > 1
+}
+
+func containsUser(users []string, user string) bool {
+	for _, u := range users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildInlineKeyboard builds inline keyboard for category selection, with
+// a "Paid by" row when there is more than one member to choose from
+func BuildInlineKeyboard(categories []string, members []string, messageID string) tgbotapi.InlineKeyboardMarkup {
 	var rows [][]tgbotapi.InlineKeyboardButton
 	
 	// Create 2 buttons per row for categories
@@ -52,13 +272,179 @@ func BuildInlineKeyboard(categories []string, messageID string) tgbotapi.InlineK
 		rows = append(rows, row)
 	}
 	
-	// Add delete button as a separate row
+	// Split options row
+	splitRow := []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⚖️ Equal", fmt.Sprintf("split_equal_%s", messageID)),
+		tgbotapi.NewInlineKeyboardButtonData("👤 Only me", fmt.Sprintf("split_me_%s", messageID)),
+		tgbotapi.NewInlineKeyboardButtonData("👥 Only them", fmt.Sprintf("split_them_%s", messageID)),
+	}
+	rows = append(rows, splitRow)
+	
+	// Paid by rows, 3 members per row. The username goes last in the
+	// callback data because it may contain underscores.
+	if len(members) > 1 {
+		for i := 0; i < len(members); i += 3 {
+			var row []tgbotapi.InlineKeyboardButton
+			for _, member := range members[i:min(i+3, len(members))] {
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+					"💳 "+member,
+					fmt.Sprintf("payer_%s_%s", messageID, member),
+				))
+			}
+			rows = append(rows, row)
+		}
+	}
+	
+	// Add delete and edit buttons as a separate row
 	deleteBtn := tgbotapi.NewInlineKeyboardButtonData(
 		"🗑️ Delete Transaction",
 		fmt.Sprintf("delete_%s", messageID),
 	)
-	deleteRow := []tgbotapi.InlineKeyboardButton{deleteBtn}
+	editBtn := tgbotapi.NewInlineKeyboardButtonData("✏️ Edit", fmt.Sprintf("edit_%s", messageID))
+	deleteRow := []tgbotapi.InlineKeyboardButton{deleteBtn, editBtn}
 	rows = append(rows, deleteRow)
 	
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
-}
\ No newline at end of file
+}
+
+// BuildBatchKeyboard builds the keyboard for a message with several
+// transactions: a row of category emoji for each line with a category,
+// then a delete and an edit button per line. Buttons start with the line
+// number.
+func BuildBatchKeyboard(categories []string, transactions []models.Transaction) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, tx := range transactions {
+		if !tx.HasCategory() {
+			continue
+		}
+		var row []tgbotapi.InlineKeyboardButton
+		for _, category := range categories {
+			icon := strings.TrimSpace(strings.TrimPrefix(category, CategoryName(category)))
+			if icon == "" {
+				icon = category
+			}
+			if category == tx.Category {
+				icon = "✅" + icon
+			}
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%d %s", tx.Line, icon),
+				fmt.Sprintf("category_%s_%s", category, tx.ID),
+			))
+		}
+		rows = append(rows, row)
+	}
+
+	// Delete and edit buttons, 2 lines per row
+	for i := 0; i < len(transactions); i += 2 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, tx := range transactions[i:min(i+2, len(transactions))] {
+			row = append(row,
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑️ %d", tx.Line), fmt.Sprintf("delete_%s", tx.ID)),
+				tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("✏️ %d", tx.Line), fmt.Sprintf("edit_%s", tx.ID)),
+			)
+		}
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// BuildSettleKeyboard builds one button per suggested transfer. The callback
+// carries the transfer's position and amount in cents so a stale button can
+// be detected when it is tapped.
+func BuildSettleKeyboard(transfers []models.Transfer, home string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, t := range transfers {
+		btn := tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("✅ %s paid %s %s", t.From, t.To, FormatHome(t.Amount, home)),
+			fmt.Sprintf("settle_%d_%d", i, int64(t.Amount)),
+		)
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{btn})
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// BuildPageKeyboard builds previous and next buttons for a paged message.
+// The callback carries the page to show, e.g. "search_2"; the page counter
+// in between does nothing.
+func BuildPageKeyboard(prefix string, page, pages int) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️ Prev", fmt.Sprintf("%s_%d", prefix, page-1)))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d/%d", page+1, pages), prefix+"_current"))
+	if page < pages-1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("Next ▶️", fmt.Sprintf("%s_%d", prefix, page+1)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(row)
+}
+
+// BuildDeleteKeyboard builds the keyboard for transactions without a
+// category keyboard: delete and edit buttons
+func BuildDeleteKeyboard(messageID string) tgbotapi.InlineKeyboardMarkup {
+	deleteBtn := tgbotapi.NewInlineKeyboardButtonData(
+		"🗑️ Delete Transaction",
+		fmt.Sprintf("delete_%s", messageID),
+	)
+	editBtn := tgbotapi.NewInlineKeyboardButtonData("✏️ Edit", fmt.Sprintf("edit_%s", messageID))
+	return tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{deleteBtn, editBtn})
+}
+
+// BuildResetKeyboard builds the confirmation buttons of a /reset request
+func BuildResetKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("✅ Yes, reset", "reset_confirm"),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Cancel", "reset_cancel"),
+	})
+}
+
+// BuildUndoKeyboard builds the keyboard of a deletion notice
+func BuildUndoKeyboard(messageID string) tgbotapi.InlineKeyboardMarkup {
+	undoBtn := tgbotapi.NewInlineKeyboardButtonData("↩️ Undo", fmt.Sprintf("undo_%s", messageID))
+	return tgbotapi.NewInlineKeyboardMarkup([]tgbotapi.InlineKeyboardButton{undoBtn})
+}
+
+// BuildTrashKeyboard builds a restore button for each numbered line of
+// the /trash list, 5 per row
+func BuildTrashKeyboard(transactions []models.Transaction) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(transactions); i += 5 {
+		var row []tgbotapi.InlineKeyboardButton
+		for j := i; j < min(i+5, len(transactions)); j++ {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("♻️ %d", j+1),
+				fmt.Sprintf("restore_%s", transactions[j].ID),
+			))
+		}
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// BuildEditMenuKeyboard builds the menu of fields a transaction can have
+// changed. Callbacks are "editset_<field>_<id>" and "editback_<id>".
+func BuildEditMenuKeyboard(tx *models.Transaction) tgbotapi.InlineKeyboardMarkup {
+	button := func(label, field string) tgbotapi.InlineKeyboardButton {
+		return tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("editset_%s_%s", field, tx.ID))
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		{button("💵 Amount", "amount"), button("📅 Date", "date"), button("📝 Note", "note")},
+	}
+	var row []tgbotapi.InlineKeyboardButton
+	if !tx.IsSettlement() {
+		row = append(row, button("💳 Payer", "payer"))
+	}
+	if tx.HasCategory() || tx.Kind == models.KindIncome {
+		row = append(row, button("⚖️ Split", "split"))
+	}
+	if tx.HasCategory() {
+		row = append(row, button("🗂️ Category", "category"))
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("↩️ Back", fmt.Sprintf("editback_%s", tx.ID)),
+	})
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+)
+
+var testOptions = ParseOptions{
+	Categories: []string{"Groceries 🛒", "Dining Out 🍽", "Transport 🚌"},
+	Aliases:    map[string]string{"costco": "Groceries 🛒"},
+	Now:        time.Date(2025, time.March, 14, 9, 15, 0, 0, time.UTC), // A Friday
+}
+
+func TestValidateAmount(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    models.Money
+		wantErr bool
+	}{
+		{text: "25", want: 2500},
+		{text: "25.5", want: 2550},
+		{text: "12,99", want: 1299},
+		{text: "1,000", want: 100000},
+		{text: "100/3", want: 3333},
+		{text: "12.50+8.99+3", want: 2449},
+		{text: "0", wantErr: true},
+		{text: "-5", wantErr: true},
+		{text: "5-10", wantErr: true},
+		{text: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ValidateAmount(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateAmount(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ValidateAmount(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseTransaction(t *testing.T) {
+	yesterday := testOptions.Now.AddDate(0, 0, -1)
+
+	tests := []struct {
+		text    string
+		want    ParsedTransaction
+		wantErr bool
+	}{
+		{
+			text: "25.50",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 2550},
+		},
+		{
+			text: "12.50+8.99+3",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 2449, Expression: "12.50+8.99+3"},
+		},
+		{
+			text: "25.50 groceries",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 2550, Category: "Groceries 🛒"},
+		},
+		{
+			text: "40 dining out with friends",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 4000, Category: "Dining Out 🍽", Note: "with friends"},
+		},
+		{
+			text: "80 costco",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 8000, Category: "Groceries 🛒", Note: "costco"},
+		},
+		{
+			text: "12 taxi yesterday",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 1200, Note: "taxi", Date: yesterday},
+		},
+		{
+			text: "12 bus 2025-03-10",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 1200, Note: "bus",
+				Date: time.Date(2025, time.March, 10, 9, 15, 0, 0, time.UTC)},
+		},
+		{
+			text: "25 EUR at 1.47",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 2500, Currency: "EUR", Rate: 1.47},
+		},
+		{
+			text: "€25",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 2500, Currency: "EUR"},
+		},
+		{
+			text: "60 @bob paid for @alice @carol",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 6000, Payer: "bob", Beneficiaries: []string{"alice", "carol"}},
+		},
+		{
+			text: "60 paid by @bob",
+			want: ParsedTransaction{Kind: models.KindExpense, Amount: 6000, Payer: "bob"},
+		},
+		{
+			text: "refund 15 groceries",
+			want: ParsedTransaction{Kind: models.KindRefund, Amount: 1500, Category: "Groceries 🛒"},
+		},
+		{
+			text: "income 200 groceries",
+			want: ParsedTransaction{Kind: models.KindIncome, Amount: 20000, Note: "groceries"},
+		},
+		{
+			text: "paid @bob 120",
+			want: ParsedTransaction{Kind: models.KindSettlement, Amount: 12000, To: "bob"},
+		},
+		{
+			text: "paid 120 @bob",
+			want: ParsedTransaction{Kind: models.KindSettlement, Amount: 12000, To: "bob"},
+		},
+		{text: "", wantErr: true},
+		{text: "hello", wantErr: true},
+		{text: "2025-03-14", wantErr: true},
+		{text: "refund", wantErr: true},
+		{text: "paid 120", wantErr: true},
+		{text: "paid @bob 120 @carol paid", wantErr: true},
+		{text: "25 @bob", wantErr: true},
+		{text: "25 tomorrow 2025-04-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseTransaction(tt.text, testOptions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTransaction(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseTransaction(%q) = %+v, want %+v", tt.text, *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+)
+
+// months maps the month words accepted in searches
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// ParseSearchQuery parses the text after /search. Supported filters, in
+// any order:
+//
+//	costco            words that must appear in the note
+//	groceries         a category, by its name
+//	@alice            logged or paid by a member
+//	85 | >50 | <100   an exact amount, a minimum or a maximum
+//	50-100            an amount range
+//	yesterday         a day, as accepted in transaction messages
+//	january | 2025-01 a month, the most recent one for a month name
+//	since january     from a day or month on ("from" and "after" work too)
+//	until 2025-03-14  up to and including a day or month ("to" too)
+//	before march      up to but not including a day or month
+func ParseSearchQuery(text string, opts ParseOptions) (*models.SearchFilter, error) {
+	filter := &models.SearchFilter{}
+	fields := strings.Fields(text)
+
+	for i := 0; i < len(fields); i++ {
+		word := strings.ToLower(strings.Trim(fields[i], ",.;:!?"))
+		switch word {
+		case "since", "from", "after", "until", "to", "before":
+			if i+1 >= len(fields) {
+				break
+			}
+			start, end, ok, err := searchPeriod(fields[i+1], opts.Now)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			switch word {
+			case "since", "from", "after":
+				filter.From = start.Unix()
+			case "until", "to":
+				filter.Until = end.Unix()
+			case "before":
+				filter.Until = start.Unix()
+			}
+			i++
+			continue
+		}
+
+		start, end, ok, err := searchPeriod(fields[i], opts.Now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			filter.From, filter.Until = start.Unix(), end.Unix()
+			continue
+		}
+
+		if isMention(word) {
+			filter.User = strings.TrimSuffix(fields[i][1:], ",")
+			continue
+		}
+		if parseAmountFilter(word, filter) {
+			continue
+		}
+		if category, byAlias, ok := MatchCategory(word, opts); ok && !byAlias {
+			filter.Category = category
+			continue
+		}
+		if word != "" {
+			filter.Words = append(filter.Words, word)
+		}
+	}
+
+	if filter.MaxAmount > 0 && filter.MinAmount > filter.MaxAmount {
+		return nil, fmt.Errorf("the smallest amount is above the largest")
+	}
+	if filter.From != 0 && filter.Until != 0 && filter.From >= filter.Until {
+		return nil, fmt.Errorf("the start date is after the end date")
+	}
+	return filter, nil
+}
+
+// searchPeriod reads a day or month word as the period [start, end)
+func searchPeriod(word string, now time.Time) (time.Time, time.Time, bool, error) {
+	lower := strings.ToLower(word)
+	if month, found := months[lower]; found {
+		year := now.Year()
+		if month > now.Month() {
+			year--
+		}
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0), true, nil
+	}
+	if month, err := time.ParseInLocation("2006-01", lower, now.Location()); err == nil {
+		return month, month.AddDate(0, 1, 0), true, nil
+	}
+
+	date, ok, err := ParseDate(word, now)
+	if err != nil || !ok {
+		return time.Time{}, time.Time{}, ok, err
+	}
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 0, 1), true, nil
+}
+
+// parseAmountFilter reads "85", ">50", "<100" or "50-100", with an
+// optional "$", into filter
+func parseAmountFilter(word string, filter *models.SearchFilter) bool {
+	word = strings.Trim(word, "$")
+	switch {
+	case strings.HasPrefix(word, ">"):
+		amount, err := ValidateAmount(strings.Trim(word[1:], "=$"))
+		if err != nil {
+			return false
+		}
+		filter.MinAmount = amount
+		if !strings.HasPrefix(word, ">=") {
+			filter.MinAmount++
+		}
+		return true
+	case strings.HasPrefix(word, "<"):
+		amount, err := ValidateAmount(strings.Trim(word[1:], "=$"))
+		if err != nil {
+			return false
+		}
+		filter.MaxAmount = amount
+		if !strings.HasPrefix(word, "<=") {
+			filter.MaxAmount--
+		}
+		return true
+	}
+
+	if low, high, found := strings.Cut(word, "-"); found {
+		lowAmount, err1 := ValidateAmount(strings.Trim(low, "$"))
+		highAmount, err2 := ValidateAmount(strings.Trim(high, "$"))
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		filter.MinAmount, filter.MaxAmount = lowAmount, highAmount
+		return true
+	}
+
+	amount, err := models.ParseMoney(normalizeNumber(word))
+	if err != nil || amount <= 0 {
+		return false
+	}
+	filter.MinAmount, filter.MaxAmount = amount, amount
+	return true
+}
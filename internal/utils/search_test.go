@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"telegram-expense-bot/internal/models"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	day := func(month time.Month, d int) int64 {
+		return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC).Unix()
+	}
+
+	tests := []struct {
+		query   string
+		want    models.SearchFilter
+		wantErr bool
+	}{
+		{query: "", want: models.SearchFilter{}},
+		{query: "Costco", want: models.SearchFilter{Words: []string{"costco"}}},
+		{query: "costco rotisserie", want: models.SearchFilter{Words: []string{"costco", "rotisserie"}}},
+		{query: "groceries", want: models.SearchFilter{Category: "Groceries 🛒"}},
+		{query: "@alice", want: models.SearchFilter{User: "alice"}},
+		{query: "85", want: models.SearchFilter{MinAmount: 8500, MaxAmount: 8500}},
+		{query: "$12,99", want: models.SearchFilter{MinAmount: 1299, MaxAmount: 1299}},
+		{query: ">50", want: models.SearchFilter{MinAmount: 5001}},
+		{query: ">=50", want: models.SearchFilter{MinAmount: 5000}},
+		{query: "<100", want: models.SearchFilter{MaxAmount: 9999}},
+		{query: "50-100", want: models.SearchFilter{MinAmount: 5000, MaxAmount: 10000}},
+		{query: "yesterday", want: models.SearchFilter{From: day(time.March, 13), Until: day(time.March, 14)}},
+		{query: "january", want: models.SearchFilter{From: day(time.January, 1), Until: day(time.February, 1)}},
+		{
+			query: "december", // The most recent December is last year's
+			want:  models.SearchFilter{From: time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC).Unix(), Until: day(time.January, 1)},
+		},
+		{query: "2025-02", want: models.SearchFilter{From: day(time.February, 1), Until: day(time.March, 1)}},
+		{query: "since feb", want: models.SearchFilter{From: day(time.February, 1)}},
+		{query: "until 2025-03-10", want: models.SearchFilter{Until: day(time.March, 11)}},
+		{query: "before march", want: models.SearchFilter{Until: day(time.March, 1)}},
+		{
+			query: "costco @bob >20 from jan to feb",
+			want: models.SearchFilter{Words: []string{"costco"}, User: "bob", MinAmount: 2001,
+				From: day(time.January, 1), Until: day(time.March, 1)},
+		},
+		{query: "since", want: models.SearchFilter{Words: []string{"since"}}},
+		{query: ">20 <10", wantErr: true},
+		{query: "since march before february", wantErr: true},
+		{query: "2025-04-01", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := ParseSearchQuery(tt.query, testOptions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSearchQuery(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseSearchQuery(%q) = %+v, want %+v", tt.query, *got, tt.want)
+			}
+		})
+	}
+}
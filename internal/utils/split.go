@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"telegram-expense-bot/internal/models"
+)
+
+// ParseSplit parses a split definition for a transaction of the given
+// amount paid by author. Members are everyone the split can name; order is
+// the configured MEMBERS list, which a bare ratio follows. Supported forms:
+//
+//	equal                      everyone pays the same
+//	me | only me               only the author
+//	them | only them           everyone except the author
+//	70/30                      percentages (or shares if they don't add up to 100), in MEMBERS order
+//	alice:60,bob:40            per-member percentages or shares
+//	shares alice:2,bob:1       per-member shares
+//	exact alice:12.50,bob:7.50 per-member amounts adding up to the total
+func ParseSplit(spec string, members, order []string, author string, amount models.Money) (*models.Split, error) {
+	spec = strings.TrimSpace(spec)
+	lower := strings.ToLower(spec)
+
+	switch lower {
+	case "", "equal", "even":
+		return &models.Split{Mode: models.SplitEqual}, nil
+	case "me", "only me":
+		return &models.Split{Mode: models.SplitShares, Weights: map[string]float64{author: 1}}, nil
+	case "them", "only them":
+		weights := make(map[string]float64)
+		for _, member := range members {
+			if member != author {
+				weights[member] = 1
+			}
+		}
+		if len(weights) == 0 {
+			return nil, fmt.Errorf("there is no one else to split with")
+		}
+		return &models.Split{Mode: models.SplitShares, Weights: weights}, nil
+	}
+
+	mode := ""
+	for _, prefix := range []string{models.SplitExact, models.SplitShares, models.SplitPercent} {
+		if strings.HasPrefix(lower, prefix+" ") {
+			mode = prefix
+			spec = strings.TrimSpace(spec[len(prefix):])
+			break
+		}
+	}
+
+	var weights map[string]float64
+	var err error
+	if strings.Contains(spec, ":") {
+		weights, err = parseNamedWeights(spec)
+	} else {
+		weights, err = parseRatio(spec, order)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("split needs at least one positive part")
+	}
+
+	if mode == "" {
+		mode = models.SplitShares
+		if math.Abs(total-100) < 0.001 {
+			mode = models.SplitPercent
+		}
+	}
+	split := &models.Split{Mode: mode, Weights: weights}
+	if err := CheckSplit(split, amount); err != nil {
+		return nil, err
+	}
+	return split, nil
+}
+
+// CheckSplit checks that a split fits a transaction of the given amount:
+// percentages add up to 100 and exact parts to the amount itself
+func CheckSplit(split *models.Split, amount models.Money) error {
+	if split.IsEqual() {
+		return nil
+	}
+	total := 0.0
+	for _, weight := range split.Weights {
+		total += weight
+	}
+
+	switch split.Mode {
+	case models.SplitPercent:
+		if math.Abs(total-100) >= 0.001 {
+			return fmt.Errorf("percentages add up to %.2f, not 100", total)
+		}
+	case models.SplitExact:
+		// Exact parts are amounts, so they must match to the cent
+		if models.FromFloat(total) != amount {
+			return fmt.Errorf("amounts add up to %s, not %s", models.FromFloat(total), amount)
+		}
+	}
+	return nil
+}
+
+// parseNamedWeights parses "alice:60,@bob:40" (commas or spaces between parts)
+func parseNamedWeights(spec string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, part := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' }) {
+		name, value, ok := strings.Cut(part, ":")
+		name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid split part %q, use user:amount", part)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid split part %q", part)
+		}
+		weights[name] += weight
+	}
+	return weights, nil
+}
+
+// parseRatio parses "70/30", assigning the parts to the configured members
+// in order. Without MEMBERS there is no order to follow, since who has
+// transactions changes as people join.
+func parseRatio(spec string, members []string) (map[string]float64, error) {
+	parts := strings.Split(spec, "/")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid split %q", spec)
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("split %q needs MEMBERS to be set, or name the parts, e.g. alice:%s", spec, strings.TrimSpace(parts[0]))
+	}
+	if len(parts) != len(members) {
+		return nil, fmt.Errorf("split %q has %d parts but MEMBERS lists %d members", spec, len(parts), len(members))
+	}
+
+	weights := make(map[string]float64)
+	for i, part := range parts {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid split part %q", part)
+		}
+		if weight > 0 {
+			weights[members[i]] = weight
+		}
+	}
+	return weights, nil
+}
+
+// DescribeSplit renders a split for bot messages
+func DescribeSplit(split *models.Split) string {
+	if split.IsEqual() {
+		return "equal"
+	}
+
+	users := make([]string, 0, len(split.Weights))
+	for user := range split.Weights {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	if len(users) == 1 {
+		return "only " + users[0]
+	}
+
+	parts := make([]string, 0, len(users))
+	for _, user := range users {
+		weight := split.Weights[user]
+		switch split.Mode {
+		case models.SplitPercent:
+			parts = append(parts, fmt.Sprintf("%s %s%%", user, strconv.FormatFloat(weight, 'f', -1, 64)))
+		case models.SplitExact:
+			parts = append(parts, fmt.Sprintf("%s %.2f", user, weight))
+		default:
+			parts = append(parts, fmt.Sprintf("%s ×%s", user, strconv.FormatFloat(weight, 'f', -1, 64)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
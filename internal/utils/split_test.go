@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"telegram-expense-bot/internal/models"
+)
+
+func TestParseSplit(t *testing.T) {
+	members := []string{"alice", "bob", "carol"}
+
+	tests := []struct {
+		name    string
+		spec    string
+		order   []string
+		amount  models.Money
+		want    *models.Split
+		wantErr bool
+	}{
+		{name: "equal", spec: "equal", want: &models.Split{Mode: models.SplitEqual}},
+		{name: "empty", spec: "", want: &models.Split{Mode: models.SplitEqual}},
+		{
+			name: "only me",
+			spec: "only me",
+			want: &models.Split{Mode: models.SplitShares, Weights: map[string]float64{"alice": 1}},
+		},
+		{
+			name: "them",
+			spec: "them",
+			want: &models.Split{Mode: models.SplitShares, Weights: map[string]float64{"bob": 1, "carol": 1}},
+		},
+		{
+			name:  "ratio follows MEMBERS",
+			spec:  "50/30/20",
+			order: []string{"carol", "alice", "bob"},
+			want:  &models.Split{Mode: models.SplitPercent, Weights: map[string]float64{"carol": 50, "alice": 30, "bob": 20}},
+		},
+		{
+			name:  "ratio of shares",
+			spec:  "2/1/0",
+			order: members,
+			want:  &models.Split{Mode: models.SplitShares, Weights: map[string]float64{"alice": 2, "bob": 1}},
+		},
+		{name: "ratio without MEMBERS", spec: "70/30", wantErr: true},
+		{name: "ratio with too few parts", spec: "70/30", order: members, wantErr: true},
+		{
+			name: "named percentages",
+			spec: "alice:60, @bob:40",
+			want: &models.Split{Mode: models.SplitPercent, Weights: map[string]float64{"alice": 60, "bob": 40}},
+		},
+		{
+			name: "named shares",
+			spec: "shares alice:2 bob:1",
+			want: &models.Split{Mode: models.SplitShares, Weights: map[string]float64{"alice": 2, "bob": 1}},
+		},
+		{name: "percentages off", spec: "percent alice:60,bob:30", wantErr: true},
+		{
+			name:   "exact",
+			spec:   "exact alice:12.50,bob:7.50",
+			amount: 2000,
+			want:   &models.Split{Mode: models.SplitExact, Weights: map[string]float64{"alice": 12.5, "bob": 7.5}},
+		},
+		{name: "exact off by a cent", spec: "exact alice:12.50,bob:7.49", amount: 2000, wantErr: true},
+		{name: "all zero", spec: "alice:0,bob:0", wantErr: true},
+		{name: "missing name", spec: ":50", wantErr: true},
+		{name: "negative part", spec: "alice:-5,bob:10", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSplit(tt.spec, members, tt.order, "alice", tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSplit(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSplit(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
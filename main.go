@@ -22,9 +22,9 @@ func main() {
 
 	// Initialize database
 	ctx := context.Background()
-	db, err := database.New(ctx, cfg.MongoURI, cfg.MongoDB, "transactions")
+	db, err := database.Open(ctx, cfg)
 	if err != nil {
-		log.Fatal("Failed to initialize MongoDB:", err)
+		log.Fatal("Failed to initialize storage:", err)
 	}
 	defer db.Close(ctx)
 
@@ -41,15 +41,31 @@ func main() {
 	eventHandler := handlers.NewEventHandler(db, cfg)
 	commandHandler := handlers.NewCommandHandler(db, cfg)
 
-	// Set up cron job for monthly reset
-	c := cron.New()
-	_, err = c.AddFunc("0 9 1 * *", func() {
-		log.Println("Executing monthly reset...")
+	// Set up cron job for the periodic reset, in the household's timezone
+	c := cron.New(cron.WithLocation(cfg.Location))
+	_, err = c.AddFunc(cfg.ResetSchedule, func() {
+		log.Println("Executing periodic reset...")
 		commandHandler.MonthlyReset(bot)
 	})
 	if err != nil {
 		log.Fatal("Failed to add cron job:", err)
 	}
+	// Purge transactions deleted longer ago than the retention period
+	_, err = c.AddFunc("0 4 * * *", commandHandler.PurgeTrash)
+	if err != nil {
+		log.Fatal("Failed to add cron job:", err)
+	}
+	// Remove transactions whose message was deleted, which Telegram doesn't
+	// report to bots
+	_, err = c.AddFunc("*/10 * * * *", func() {
+		eventHandler.Reconcile(bot)
+	})
+	if err != nil {
+		log.Fatal("Failed to add cron job:", err)
+	}
+
+	// Run the resets missed while the bot was down before the schedule starts
+	commandHandler.CatchUpResets(bot)
 	c.Start()
 
 	fmt.Println("Bot is running...")
@@ -63,13 +79,7 @@ func main() {
 	// Handle updates
 	go func() {
 		for update := range updates {
-			if update.Message != nil {
-				eventHandler.HandleMessage(bot, update.Message)
-			} else if update.EditedMessage != nil {
-				eventHandler.HandleMessage(bot, update.EditedMessage)
-			} else if update.CallbackQuery != nil {
-				eventHandler.HandleCallbackQuery(bot, update.CallbackQuery)
-			}
+			eventHandler.HandleUpdate(bot, update)
 		}
 	}()
 